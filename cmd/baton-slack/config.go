@@ -7,8 +7,7 @@ import (
 var (
 	AccessTokenField = field.StringField(
 		"token",
-		field.WithDescription("The Slack bot user oauth token used to connect to the Slack API"),
-		field.WithRequired(true),
+		field.WithDescription("The Slack bot user oauth token used to connect to the Slack API. Not needed with --admin-api-only"),
 	)
 	EnterpriseTokenField = field.StringField(
 		"enterprise-token",
@@ -20,9 +19,252 @@ var (
 		field.WithDefaultValue(false),
 	)
 
+	InactiveDaysField = field.IntField(
+		"inactive-days",
+		field.WithDescription("Flag users that haven't accessed Slack in this many days as inactive. Requires the enterprise token. 0 disables the check"),
+		field.WithDefaultValue(0),
+	)
+	GuestExpirationDaysField = field.IntField(
+		"guest-expiration-days",
+		field.WithDescription("Deactivate guests this many days after they are granted a guest role. Requires Enterprise Grid. 0 leaves their expiration unchanged"),
+		field.WithDefaultValue(0),
+	)
+	SyncLastLoginField = field.BoolField(
+		"sync-last-login",
+		field.WithDescription("Set the last login of every user from the workspace access logs. Requires the enterprise token"),
+		field.WithDefaultValue(false),
+	)
+	ServiceAccountPatternsField = field.StringSliceField(
+		"service-account-patterns",
+		field.WithDescription("Regular expressions matched against user names and emails. Matching users are synced as service accounts"),
+	)
+	SyncPresenceField = field.BoolField(
+		"sync-presence",
+		field.WithDescription("Add the current presence of every user to their profile. Makes one extra API call per user"),
+		field.WithDefaultValue(false),
+	)
+	SyncEmployeeNumbersField = field.BoolField(
+		"sync-employee-numbers",
+		field.WithDescription("Add the employeeNumber of the SCIM enterprise extension to every user profile. Requires --sso-enabled"),
+		field.WithDefaultValue(false),
+	)
+	AnalyticsField = field.BoolField(
+		"analytics",
+		field.WithDescription("Add the messages posted, days active and last active day of the last week from the member analytics files to every user profile. Requires Enterprise Grid"),
+		field.WithDefaultValue(false),
+	)
+	ResolveBotOwnersField = field.BoolField(
+		"resolve-bot-owners",
+		field.WithDescription("Add the app every bot user belongs to and the admin who approved it to the bot's profile. Requires Enterprise Grid"),
+		field.WithDefaultValue(false),
+	)
+	ExcludedProfileFieldsField = field.StringSliceField(
+		"excluded-profile-fields",
+		field.WithDescription("User profile fields to omit from the sync, e.g. status_text"),
+	)
+	AuditChannelField = field.StringField(
+		"audit-channel-id",
+		field.WithDescription("ID of a Slack channel where every provisioning operation performed by the connector is posted"),
+	)
+	FailureWebhookField = field.StringField(
+		"failure-webhook-url",
+		field.WithDescription("URL notified with a JSON payload when a provisioning operation fails. Slack incoming webhook URLs are supported"),
+	)
+	HealthAddrField = field.StringField(
+		"health-addr",
+		field.WithDescription("Address to serve the /healthz endpoint on when running as a service, e.g. :8080"),
+	)
+	ListWorkspacesField = field.BoolField(
+		"list-workspaces",
+		field.WithDescription("Print the discovered workspaces with their member counts and which tokens can access them, then exit"),
+		field.WithDefaultValue(false),
+	)
+	ActionField = field.StringField(
+		"action",
+		field.WithDescription("Run the named action, print its output as JSON, then exit. Actions that change Slack require --provisioning"),
+	)
+	ActionArgsField = field.StringSliceField(
+		"action-args",
+		field.WithDescription("Arguments of the action as name=value pairs"),
+	)
+	AdminAPIOnlyField = field.BoolField(
+		"admin-api-only",
+		field.WithDescription("Sync an Enterprise Grid organization with the enterprise token alone, without installing the bot in every workspace"),
+		field.WithDefaultValue(false),
+	)
+	SingleWorkspaceField = field.BoolField(
+		"single-workspace",
+		field.WithDescription("Sync only the workspace the token belongs to instead of listing workspaces. Not available for Enterprise Grid"),
+		field.WithDefaultValue(false),
+	)
+	CheckpointFileField = field.StringField(
+		"checkpoint-file",
+		field.WithDescription("File where the connector persists its sync progress so that a restarted sync can resume"),
+	)
+	RoleEmissionField = field.StringField(
+		"role-emission",
+		field.WithDescription("Which roles to grant a user holding several: all, or only the highest-privilege one (highest)"),
+		field.WithDefaultValue("all"),
+	)
+	GovSlackField = field.BoolField(
+		"govslack",
+		field.WithDescription("Connect to GovSlack (slack-gov.com) instead of Slack"),
+		field.WithDefaultValue(false),
+	)
+	SCIMVersionField = field.StringField(
+		"scim-version",
+		field.WithDescription("SCIM API version used for IDP groups: v1 or v2. Defaults to v1 on GovSlack and v2 otherwise"),
+	)
+	SCIMMaxRetriesField = field.IntField(
+		"scim-max-retries",
+		field.WithDescription("How many times a rate limited SCIM request is retried, after waiting as long as Slack asks, before it fails. 0 disables the retries"),
+		field.WithDefaultValue(3),
+	)
+	MaxConcurrencyField = field.IntField(
+		"max-concurrency",
+		field.WithDescription("How many workspaces have their users fetched at once while syncing workspace grants. 1 fetches one workspace at a time"),
+		field.WithDefaultValue(4),
+	)
+	LowercaseEmailsField = field.BoolField(
+		"lowercase-emails",
+		field.WithDescription("Lowercase synced user emails"),
+		field.WithDefaultValue(false),
+	)
+	StripPlusAddressingField = field.BoolField(
+		"strip-plus-addressing",
+		field.WithDescription("Remove the +tag part of synced user emails, e.g. jane+slack@example.com becomes jane@example.com"),
+		field.WithDefaultValue(false),
+	)
+	EmailDomainAliasesField = field.StringSliceField(
+		"email-domain-aliases",
+		field.WithDescription("Email domains to rewrite in synced user emails, as alias.com=canonical.com pairs"),
+	)
+	MissingEmailField = field.StringField(
+		"missing-email",
+		field.WithDescription("How to sync users without an email: keep, skip, omit (no email trait) or placeholder"),
+		field.WithDefaultValue("keep"),
+	)
+	IncludeExternalUsersField = field.BoolField(
+		"include-external-users",
+		field.WithDescription("Sync the users of other organizations listed in a workspace, e.g. Slack Connect members, tagged as external"),
+		field.WithDefaultValue(false),
+	)
+	SyncStatsAnnotationsField = field.BoolField(
+		"sync-stats-annotations",
+		field.WithDescription("Attach the resource, entitlement, grant, skip and error counts of every sync operation to its last response"),
+		field.WithDefaultValue(false),
+	)
+	CacheSizeField = field.IntField(
+		"cache-size",
+		field.WithDescription("Maximum number of entries kept by each in-memory cache, e.g. pages of users, IDP groups or user presences"),
+		field.WithDefaultValue(10000),
+	)
+	PprofAddrField = field.StringField(
+		"pprof-addr",
+		field.WithDescription("Address to serve the Go runtime profiles on under /debug/pprof/, e.g. localhost:6060"),
+	)
+
+	SkipResourceTypesField = field.StringSliceField(
+		"skip-resource-types",
+		field.WithDescription("IDs of resource types not to sync, e.g. enterpriseRole, group or externalOrganization"),
+	)
+	EntitlementsOnlyField = field.StringSliceField(
+		"entitlements-only",
+		field.WithDescription("IDs of resource types synced with their entitlements but without grants, e.g. workspace or group"),
+	)
+	VerifyBeforeWriteField = field.BoolField(
+		"verify-before-write",
+		field.WithDescription("Check the current state before granting or revoking workspace roles and authentication policies, and skip writes that change nothing"),
+		field.WithDefaultValue(false),
+	)
+	ReadOnlyField = field.BoolField(
+		"read-only",
+		field.WithDescription("Reject every grant, revoke, account creation and action, even with --provisioning, for sync-only deployments"),
+		field.WithDefaultValue(false),
+	)
+	ChannelIncludePatternsField = field.StringSliceField(
+		"channel-include-patterns",
+		field.WithDescription("Regular expressions matched against channel names. When set, only matching channels are read"),
+	)
+	ChannelExcludePatternsField = field.StringSliceField(
+		"channel-exclude-patterns",
+		field.WithDescription("Regular expressions matched against channel names. Matching channels are skipped"),
+	)
+	MaxChannelMembersForGrantsField = field.IntField(
+		"max-channel-members-for-grants",
+		field.WithDescription("Sync channels with more members than this without their member grants, with the member count in their profile. 0 syncs every channel's grants"),
+		field.WithDefaultValue(0),
+	)
+	UserGroupChannelGrantsField = field.BoolField(
+		"user-group-channel-grants",
+		field.WithDescription("Grant channels to the user groups they are a default channel of, expanded to their members, instead of to each member"),
+		field.WithDefaultValue(false),
+	)
+	RetryQueueFileField = field.StringField(
+		"retry-queue-file",
+		field.WithDescription("File where grants and revokes that fail with a transient error are queued and retried with backoff"),
+	)
+
+	EventsAddrField = field.StringField(
+		"events-addr",
+		field.WithDescription("Address to receive Slack Events API requests on under /slack/events, e.g. :3000, to pick up user changes between full syncs"),
+	)
+	EventsSigningSecretField = field.StringField(
+		"events-signing-secret",
+		field.WithDescription("Signing secret of the Slack app, used to verify Events API requests. Required with --events-addr"),
+	)
+	AuditLogsField = field.BoolField(
+		"audit-logs",
+		field.WithDescription("List user logins, channel creations, role changes and app installations from the audit logs in the event feed. Requires Enterprise Grid"),
+		field.WithDefaultValue(false),
+	)
+
 	Configuration = field.NewConfiguration([]field.SchemaField{
 		AccessTokenField,
 		EnterpriseTokenField,
 		SSOEnabledField,
+		InactiveDaysField,
+		SyncLastLoginField,
+		GuestExpirationDaysField,
+		ServiceAccountPatternsField,
+		SyncPresenceField,
+		ExcludedProfileFieldsField,
+		AuditChannelField,
+		FailureWebhookField,
+		HealthAddrField,
+		ListWorkspacesField,
+		ActionField,
+		ActionArgsField,
+		AdminAPIOnlyField,
+		SingleWorkspaceField,
+		CheckpointFileField,
+		RoleEmissionField,
+		GovSlackField,
+		SCIMVersionField,
+		SCIMMaxRetriesField,
+		MaxConcurrencyField,
+		LowercaseEmailsField,
+		StripPlusAddressingField,
+		EmailDomainAliasesField,
+		MissingEmailField,
+		IncludeExternalUsersField,
+		SyncEmployeeNumbersField,
+		AnalyticsField,
+		SyncStatsAnnotationsField,
+		CacheSizeField,
+		PprofAddrField,
+		VerifyBeforeWriteField,
+		SkipResourceTypesField,
+		EntitlementsOnlyField,
+		ResolveBotOwnersField,
+		ReadOnlyField,
+		ChannelIncludePatternsField,
+		ChannelExcludePatternsField,
+		MaxChannelMembersForGrantsField,
+		UserGroupChannelGrantsField,
+		RetryQueueFileField,
+		EventsAddrField,
+		EventsSigningSecretField,
+		AuditLogsField,
 	})
 )
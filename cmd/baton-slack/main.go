@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 
 	"github.com/conductorone/baton-sdk/pkg/config"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
@@ -44,22 +47,181 @@ func main() {
 
 func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, error) {
 	logger := ctxzap.Extract(ctx)
+
+	serviceAccountPatterns, err := compilePatterns(v.GetStringSlice(ServiceAccountPatternsField.FieldName))
+	if err != nil {
+		logger.Error("invalid service account pattern", zap.Error(err))
+		return nil, err
+	}
+
+	channelIncludePatterns, err := compilePatterns(v.GetStringSlice(ChannelIncludePatternsField.FieldName))
+	if err != nil {
+		logger.Error("invalid channel include pattern", zap.Error(err))
+		return nil, err
+	}
+
+	channelExcludePatterns, err := compilePatterns(v.GetStringSlice(ChannelExcludePatternsField.FieldName))
+	if err != nil {
+		logger.Error("invalid channel exclude pattern", zap.Error(err))
+		return nil, err
+	}
+
+	domainAliases, err := connector.ParseDomainAliases(v.GetStringSlice(EmailDomainAliasesField.FieldName))
+	if err != nil {
+		logger.Error("invalid email domain alias", zap.Error(err))
+		return nil, err
+	}
+
 	cb, err := connector.New(
 		ctx,
 		v.GetString(AccessTokenField.FieldName),
 		v.GetString(EnterpriseTokenField.FieldName),
 		v.GetBool(SSOEnabledField.FieldName),
+		connector.WithInactiveDays(v.GetInt(InactiveDaysField.FieldName)),
+		connector.WithLastLogin(v.GetBool(SyncLastLoginField.FieldName)),
+		connector.WithGuestExpirationDays(v.GetInt(GuestExpirationDaysField.FieldName)),
+		connector.WithServiceAccountPatterns(serviceAccountPatterns),
+		connector.WithPresence(v.GetBool(SyncPresenceField.FieldName)),
+		connector.WithExcludedProfileFields(v.GetStringSlice(ExcludedProfileFieldsField.FieldName)),
+		connector.WithAuditChannel(v.GetString(AuditChannelField.FieldName)),
+		connector.WithFailureWebhook(v.GetString(FailureWebhookField.FieldName)),
+		connector.WithHealthAddr(v.GetString(HealthAddrField.FieldName)),
+		connector.WithAdminAPIOnly(v.GetBool(AdminAPIOnlyField.FieldName)),
+		connector.WithSingleWorkspace(v.GetBool(SingleWorkspaceField.FieldName)),
+		connector.WithCheckpointFile(v.GetString(CheckpointFileField.FieldName)),
+		connector.WithRoleEmission(v.GetString(RoleEmissionField.FieldName)),
+		connector.WithGovSlack(v.GetBool(GovSlackField.FieldName)),
+		connector.WithSCIMVersion(v.GetString(SCIMVersionField.FieldName)),
+		connector.WithSCIMMaxRetries(v.GetInt(SCIMMaxRetriesField.FieldName)),
+		connector.WithMaxConcurrency(v.GetInt(MaxConcurrencyField.FieldName)),
+		connector.WithEmailNormalization(
+			v.GetBool(LowercaseEmailsField.FieldName),
+			v.GetBool(StripPlusAddressingField.FieldName),
+			domainAliases,
+		),
+		connector.WithMissingEmail(v.GetString(MissingEmailField.FieldName)),
+		connector.WithExternalUsers(v.GetBool(IncludeExternalUsersField.FieldName)),
+		connector.WithEmployeeNumbers(v.GetBool(SyncEmployeeNumbersField.FieldName)),
+		connector.WithAnalytics(v.GetBool(AnalyticsField.FieldName)),
+		connector.WithSyncStatsAnnotations(v.GetBool(SyncStatsAnnotationsField.FieldName)),
+		connector.WithCacheSize(v.GetInt(CacheSizeField.FieldName)),
+		connector.WithPprofAddr(v.GetString(PprofAddrField.FieldName)),
+		connector.WithVerifyBeforeWrite(v.GetBool(VerifyBeforeWriteField.FieldName)),
+		connector.WithSkippedResourceTypes(v.GetStringSlice(SkipResourceTypesField.FieldName)),
+		connector.WithEntitlementsOnly(v.GetStringSlice(EntitlementsOnlyField.FieldName)),
+		connector.WithBotOwners(v.GetBool(ResolveBotOwnersField.FieldName)),
+		connector.WithReadOnly(v.GetBool(ReadOnlyField.FieldName)),
+		connector.WithChannelFilters(channelIncludePatterns, channelExcludePatterns),
+		connector.WithMaxChannelMembersForGrants(v.GetInt(MaxChannelMembersForGrantsField.FieldName)),
+		connector.WithUserGroupChannelGrants(v.GetBool(UserGroupChannelGrantsField.FieldName)),
+		connector.WithRetryQueueFile(v.GetString(RetryQueueFileField.FieldName)),
+		connector.WithEvents(
+			v.GetString(EventsAddrField.FieldName),
+			v.GetString(EventsSigningSecretField.FieldName),
+		),
+		connector.WithAuditLogs(v.GetBool(AuditLogsField.FieldName)),
 	)
 	if err != nil {
 		logger.Error("error creating connector", zap.Error(err))
 		return nil, err
 	}
 
+	if v.GetBool(ListWorkspacesField.FieldName) {
+		listWorkspaces(ctx, cb)
+	}
+
+	if action := v.GetString(ActionField.FieldName); action != "" {
+		runAction(ctx, cb, action, v.GetStringSlice(ActionArgsField.FieldName), v.GetBool("provisioning"))
+	}
+
 	c, err := connectorbuilder.NewConnector(ctx, cb)
 	if err != nil {
 		logger.Error("error creating connector", zap.Error(err))
 		return nil, err
 	}
 
-	return c, nil
+	go func() {
+		if err := cb.ServeHealth(ctx); err != nil {
+			logger.Error("error serving health endpoint", zap.Error(err))
+		}
+	}()
+
+	go func() {
+		if err := cb.ServePprof(ctx); err != nil {
+			logger.Error("error serving pprof endpoint", zap.Error(err))
+		}
+	}()
+
+	go func() {
+		if err := cb.ServeEvents(ctx); err != nil {
+			logger.Error("error receiving Slack events", zap.Error(err))
+		}
+	}()
+
+	server := cb.WrapServer(c)
+
+	go func() {
+		if err := cb.RunRetryQueue(ctx); err != nil {
+			logger.Error("error running retry queue", zap.Error(err))
+		}
+	}()
+
+	return server, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// listWorkspaces is a diagnostic run mode that shows which workspaces a sync
+// would cover before running one.
+func listWorkspaces(ctx context.Context, cb *connector.Slack) {
+	workspaces, err := cb.DiscoverWorkspaces(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	if err := connector.PrintWorkspaces(os.Stdout, workspaces); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// runAction is a run mode that performs a single action, prints its output
+// and exits.
+func runAction(ctx context.Context, cb *connector.Slack, name string, rawArgs []string, provisioning bool) {
+	args := make(map[string]string, len(rawArgs))
+	for _, rawArg := range rawArgs {
+		key, value, ok := strings.Cut(rawArg, "=")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "invalid action argument %q, expected name=value\n", rawArg)
+			os.Exit(1)
+		}
+		args[key] = value
+	}
+
+	output, err := cb.RunAction(ctx, name, args, provisioning)
+	if output != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(output); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
 }
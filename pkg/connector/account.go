@@ -0,0 +1,302 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-slack/pkg"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// CreateAccount creates a Slack account. With SCIM, the user is created in
+// the organization right away and the account profile may hold the username,
+// first_name and last_name of the user. Otherwise the user is invited to a
+// workspace with admin.users.invite, and the account profile must hold the
+// team_id of the workspace and the channel_ids the user joins, as a list or a
+// comma-separated string.
+//
+// The response carries the resource of the new user, so that later steps can
+// refer to the new Slack identity right away, and on Enterprise Grid a link
+// to the user in the admin console. If Slack doesn't return an invited user
+// yet, the response asks for the invitation to be accepted instead.
+func (o *userResourceType) CreateAccount(
+	ctx context.Context,
+	accountInfo *v2.AccountInfo,
+	_ *v2.CredentialOptions,
+) (
+	connectorbuilder.CreateAccountResponse,
+	[]*v2.PlaintextData,
+	annotations.Annotations,
+	error,
+) {
+	email := accountEmail(accountInfo)
+	if email == "" {
+		return nil, nil, nil, fmt.Errorf("baton-slack: an email is required to create an account")
+	}
+
+	profile := accountInfo.GetProfile().AsMap()
+	teamID, _ := profile["team_id"].(string)
+	outputAnnotations := annotations.New()
+
+	var user *slack.User
+	if o.ssoEnabled {
+		firstName, _ := profile["first_name"].(string)
+		lastName, _ := profile["last_name"].(string)
+		userName := accountUserName(accountInfo, profile, email)
+		userID, ratelimitData, err := o.enterpriseClient.CreateUser(
+			ctx,
+			userName,
+			email,
+			firstName,
+			lastName,
+		)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return nil, nil, outputAnnotations, fmt.Errorf("baton-slack: error creating user: %w", err)
+		}
+
+		user, err = o.client.GetUserInfoContext(ctx, userID)
+		if err != nil {
+			// The user exists now, so failing would only make the retry fail
+			// with a SCIM conflict. Describe it with what was sent instead.
+			ctxzap.Extract(ctx).Warn(
+				"baton-slack: created user not found",
+				zap.String("user_id", userID),
+				zap.Error(pkg.WrapTokenError(err)),
+			)
+			user = scimCreatedUser(userID, userName, email, firstName, lastName)
+		}
+	} else {
+		if o.enterpriseID == "" {
+			return nil, nil, nil, fmt.Errorf("baton-slack: creating accounts requires Enterprise Grid or the SCIM API, enable it with --sso-enabled")
+		}
+		if teamID == "" {
+			return nil, nil, nil, fmt.Errorf("baton-slack: team_id is required to create an account")
+		}
+		channelIDs := profileStrings(profile["channel_ids"])
+		if len(channelIDs) == 0 {
+			return nil, nil, nil, fmt.Errorf("baton-slack: channel_ids is required to create an account")
+		}
+
+		ratelimitData, err := o.enterpriseClient.InviteUser(ctx, teamID, email, channelIDs)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return nil, nil, outputAnnotations, fmt.Errorf("baton-slack: error inviting user: %w", err)
+		}
+
+		user, err = o.client.GetUserByEmailContext(ctx, email)
+		if err != nil {
+			// The invitation went out, only the user isn't visible yet.
+			ctxzap.Extract(ctx).Warn(
+				"baton-slack: invited user not found",
+				zap.String("team_id", teamID),
+				zap.Error(pkg.WrapTokenError(err)),
+			)
+			return &v2.CreateAccountResponse_ActionRequiredResult{
+				Message:               fmt.Sprintf("Invited %s to workspace %s, the account appears once the invitation is accepted", email, teamID),
+				IsCreateAccountResult: true,
+			}, nil, outputAnnotations, nil
+		}
+	}
+
+	if teamID == "" {
+		teamID = user.TeamID
+	}
+	// A user created through SCIM on Enterprise Grid may not belong to any
+	// workspace yet.
+	var workspaceID *v2.ResourceId
+	if teamID != "" {
+		workspaceID = &v2.ResourceId{ResourceType: resourceTypeWorkspace.Id, Resource: teamID}
+	}
+
+	options := o.options
+	options.enterpriseID = o.enterpriseID
+	r, err := userResource(ctx, user, workspaceID, &options)
+	if err != nil {
+		// The account exists at this point, so the error is not reported as
+		// a failure that would be retried.
+		ctxzap.Extract(ctx).Warn(
+			"baton-slack: error building created user resource",
+			zap.String("user_id", user.ID),
+			zap.Error(err),
+		)
+		return &v2.CreateAccountResponse_ActionRequiredResult{
+			Message:               fmt.Sprintf("Created Slack user %s for %s, it is synced with the next sync", user.ID, email),
+			IsCreateAccountResult: true,
+		}, nil, outputAnnotations, nil
+	}
+
+	if o.enterpriseID != "" {
+		outputAnnotations.Append(adminUserLink(o.enterpriseID, user.ID))
+	}
+	return &v2.CreateAccountResponse_SuccessResult{
+		Resource:              r,
+		IsCreateAccountResult: true,
+	}, nil, outputAnnotations, nil
+}
+
+// scimCreatedUser describes a user created through SCIM from the attributes
+// it was created with, for when Slack doesn't return it yet.
+func scimCreatedUser(userID, userName, email, firstName, lastName string) *slack.User {
+	realName := strings.TrimSpace(firstName + " " + lastName)
+	if realName == "" {
+		realName = userName
+	}
+	return &slack.User{
+		ID:       userID,
+		Name:     userName,
+		RealName: realName,
+		Profile: slack.UserProfile{
+			Email:     email,
+			FirstName: firstName,
+			LastName:  lastName,
+			RealName:  realName,
+		},
+	}
+}
+
+// accountUserName returns the username of a SCIM account: the username from
+// the account profile, the login, or the local part of the email, in that
+// order. Slack usernames can't contain an @.
+func accountUserName(accountInfo *v2.AccountInfo, profile map[string]interface{}, email string) string {
+	if userName, ok := profile["username"].(string); ok && userName != "" {
+		return userName
+	}
+	if login := accountInfo.GetLogin(); login != "" && !strings.Contains(login, "@") {
+		return login
+	}
+	userName, _, _ := strings.Cut(email, "@")
+	return userName
+}
+
+// accountAttributes returns the SCIM attributes set in an account profile:
+// title, department, display_name and manager, the Slack user ID of the
+// manager.
+func accountAttributes(profile map[string]interface{}) enterprise.UserAttributes {
+	var rv enterprise.UserAttributes
+	rv.Title, _ = profile["title"].(string)
+	rv.Department, _ = profile["department"].(string)
+	rv.DisplayName, _ = profile["display_name"].(string)
+	rv.ManagerID, _ = profile["manager"].(string)
+	return rv
+}
+
+// accountEmail returns the primary email of the account, falling back to its
+// first email and then to its login.
+func accountEmail(accountInfo *v2.AccountInfo) string {
+	for _, email := range accountInfo.GetEmails() {
+		if email.GetIsPrimary() {
+			return email.GetAddress()
+		}
+	}
+	if emails := accountInfo.GetEmails(); len(emails) > 0 {
+		return emails[0].GetAddress()
+	}
+	return accountInfo.GetLogin()
+}
+
+// profileStrings reads a list of strings from an account profile value, given
+// either as a list or as a comma-separated string.
+func profileStrings(value interface{}) []string {
+	var rv []string
+	switch v := value.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				rv = append(rv, s)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				rv = append(rv, s)
+			}
+		}
+	}
+	return rv
+}
+
+// Deprovisioning methods reported in the annotations of Delete.
+const (
+	deprovisionSCIM        = "scim"
+	deprovisionAdminRemove = "admin.users.remove"
+)
+
+// Create is not supported, accounts are created with CreateAccount.
+func (o *userResourceType) Create(
+	_ context.Context,
+	_ *v2.Resource,
+) (*v2.Resource, annotations.Annotations, error) {
+	return nil, nil, status.Error(codes.Unimplemented, "baton-slack: users are created with account creation")
+}
+
+// Delete deprovisions an account. With SCIM, the account is deactivated in
+// the whole organization. Otherwise it is removed from every workspace it is a
+// member of with admin.users.remove. The method used, and the workspaces the
+// user was removed from, are reported in the annotations.
+func (o *userResourceType) Delete(
+	ctx context.Context,
+	resourceId *v2.ResourceId,
+) (annotations.Annotations, error) {
+	userID := resourceId.Resource
+	outputAnnotations := annotations.New()
+
+	if o.ssoEnabled {
+		ratelimitData, err := o.enterpriseClient.DeactivateUser(ctx, userID)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return outputAnnotations, fmt.Errorf("baton-slack: failed to delete user: %w", err)
+		}
+		outputAnnotations.Append(deprovisionAnnotation(deprovisionSCIM, nil))
+		return outputAnnotations, nil
+	}
+
+	if o.enterpriseID == "" {
+		return nil, fmt.Errorf("baton-slack: deleting users requires Enterprise Grid or the SCIM API, enable it with --sso-enabled")
+	}
+
+	user, ratelimitData, err := o.enterpriseClient.GetUserInfo(ctx, userID)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return outputAnnotations, fmt.Errorf("baton-slack: failed to delete user: %w", err)
+	}
+
+	teamIDs := user.Enterprise.Teams
+	if len(teamIDs) == 0 && user.TeamID != "" {
+		teamIDs = []string{user.TeamID}
+	}
+
+	removed := make([]string, 0, len(teamIDs))
+	for _, teamID := range teamIDs {
+		ratelimitData, err := o.enterpriseClient.RemoveUserFromTeam(ctx, teamID, userID)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil && !notMemberCodes[slackErrorCode(err)] {
+			return outputAnnotations, fmt.Errorf("baton-slack: failed to remove user from workspace %s: %w", teamID, err)
+		}
+		removed = append(removed, teamID)
+	}
+
+	outputAnnotations.Append(deprovisionAnnotation(deprovisionAdminRemove, removed))
+	return outputAnnotations, nil
+}
+
+// deprovisionAnnotation describes how an account was deprovisioned.
+func deprovisionAnnotation(method string, teamIDs []string) *structpb.Struct {
+	fields := map[string]*structpb.Value{
+		"deprovision_method": structpb.NewStringValue(method),
+	}
+	if teamIDs != nil {
+		fields["removed_team_ids"] = structpb.NewStringValue(strings.Join(teamIDs, ","))
+	}
+	return &structpb.Struct{Fields: fields}
+}
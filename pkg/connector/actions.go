@@ -0,0 +1,108 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// ActionArgument describes an argument taken by an action.
+type ActionArgument struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Action is an operation that isn't tied to an entitlement, run on demand
+// from a workflow rather than as part of a sync or a grant.
+type Action struct {
+	Name        string
+	Description string
+	Arguments   []ActionArgument
+	// ReadOnly actions don't change anything in Slack and can run without
+	// provisioning enabled.
+	ReadOnly bool
+
+	run func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error)
+}
+
+// actions are all the actions the connector supports, by name.
+var actions = map[string]*Action{}
+
+func registerAction(action *Action) {
+	actions[action.Name] = action
+}
+
+// Actions returns the supported actions sorted by name.
+func (s *Slack) Actions() []Action {
+	rv := make([]Action, 0, len(actions))
+	for _, action := range actions {
+		rv = append(rv, *action)
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].Name < rv[j].Name
+	})
+	return rv
+}
+
+// RunAction runs the named action and returns its structured output. Actions
+// that change Slack are recorded in the audit channel and reported to the
+// failure webhook like grants and revokes. Actions that partially fail return
+// their output along with the error.
+func (s *Slack) RunAction(
+	ctx context.Context,
+	name string,
+	args map[string]string,
+	provisioning bool,
+) (map[string]interface{}, error) {
+	action, ok := actions[name]
+	if !ok {
+		return nil, fmt.Errorf("baton-slack: unknown action %q", name)
+	}
+
+	if !action.ReadOnly && s.readOnly {
+		return nil, errReadOnly
+	}
+
+	if !action.ReadOnly && !provisioning {
+		return nil, fmt.Errorf("baton-slack: action %s changes Slack and requires provisioning to be enabled", name)
+	}
+
+	for _, argument := range action.Arguments {
+		if argument.Required && args[argument.Name] == "" {
+			return nil, fmt.Errorf("baton-slack: action %s requires the %s argument", name, argument.Name)
+		}
+	}
+
+	if !action.ReadOnly {
+		ctx = withProvisioningPriority(ctx)
+	}
+
+	output, err := action.run(ctx, s, args)
+	if !action.ReadOnly {
+		operation := "action " + name
+		s.audit.record(ctx, operation, actionPrincipal(args), actionTarget(args), err)
+		s.notifier.notify(ctx, operation, actionPrincipal(args), actionTarget(args), err)
+	}
+	return output, err
+}
+
+// actionPrincipal returns the user an action applies to, if any.
+func actionPrincipal(args map[string]string) *v2.ResourceId {
+	if userID := args["user_id"]; userID != "" {
+		return &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: userID}
+	}
+	return nil
+}
+
+// actionTarget returns what an action applies to, for the audit trail.
+func actionTarget(args map[string]string) string {
+	for _, key := range []string{"channel_id", "user_group", "user_group_id", "team_id", "app_id", "invite_id", "role", "user_ids", "email"} {
+		if args[key] != "" {
+			return key + "=" + args[key]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+)
+
+func init() {
+	registerAction(appAction(
+		"approve_app",
+		"Approve an app for installation in a workspace or the whole organization",
+		"approved",
+		func(ctx context.Context, s *Slack, appID, teamID string) error {
+			_, err := s.enterpriseClient.ApproveApp(ctx, appID, teamID)
+			return err
+		},
+	))
+	registerAction(appAction(
+		"restrict_app",
+		"Restrict an app from being installed in a workspace or the whole organization",
+		"restricted",
+		func(ctx context.Context, s *Slack, appID, teamID string) error {
+			_, err := s.enterpriseClient.RestrictApp(ctx, appID, teamID)
+			return err
+		},
+	))
+}
+
+// appAction builds the approve and restrict actions, which only differ by the
+// endpoint they call.
+func appAction(
+	name string,
+	description string,
+	state string,
+	update func(ctx context.Context, s *Slack, appID, teamID string) error,
+) *Action {
+	return &Action{
+		Name:        name,
+		Description: description,
+		Arguments: []ActionArgument{
+			{Name: "app_id", Description: "ID of the app", Required: true},
+			{Name: "team_id", Description: "ID of the workspace. Defaults to the whole organization"},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			if s.enterpriseID == "" {
+				return nil, fmt.Errorf("baton-slack: managing apps is only available on Enterprise Grid")
+			}
+
+			if err := update(ctx, s, args["app_id"], args["team_id"]); err != nil {
+				return nil, err
+			}
+
+			scope := args["team_id"]
+			if scope == "" {
+				scope = s.enterpriseID
+			}
+			return map[string]interface{}{
+				"app_id": args["app_id"],
+				"scope":  scope,
+				"state":  state,
+			}, nil
+		},
+	}
+}
@@ -0,0 +1,190 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/conductorone/baton-slack/pkg"
+	"github.com/slack-go/slack"
+)
+
+func init() {
+	registerAction(&Action{
+		Name:        "set_channel_topic",
+		Description: "Set the topic of a channel",
+		Arguments: []ActionArgument{
+			{Name: "channel_id", Description: "ID of the channel, or its name on Enterprise Grid", Required: true},
+			{Name: "text", Description: "New topic of the channel", Required: true},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			channelID, err := resolveChannelID(ctx, s, args["channel_id"])
+			if err != nil {
+				return nil, err
+			}
+
+			channel, err := s.client.SetTopicOfConversationContext(ctx, channelID, args["text"])
+			if err != nil {
+				return nil, fmt.Errorf("baton-slack: error setting channel topic: %w", pkg.WrapTokenError(err))
+			}
+			return channelOutput(channel), nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "set_channel_purpose",
+		Description: "Set the purpose (description) of a channel",
+		Arguments: []ActionArgument{
+			{Name: "channel_id", Description: "ID of the channel, or its name on Enterprise Grid", Required: true},
+			{Name: "text", Description: "New purpose of the channel", Required: true},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			channelID, err := resolveChannelID(ctx, s, args["channel_id"])
+			if err != nil {
+				return nil, err
+			}
+
+			channel, err := s.client.SetPurposeOfConversationContext(ctx, channelID, args["text"])
+			if err != nil {
+				return nil, fmt.Errorf("baton-slack: error setting channel purpose: %w", pkg.WrapTokenError(err))
+			}
+			return channelOutput(channel), nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "rename_channel",
+		Description: "Rename a channel. On Enterprise Grid the admin API is used, so the bot doesn't need to be a member of the channel",
+		Arguments: []ActionArgument{
+			{Name: "channel_id", Description: "ID of the channel, or its name on Enterprise Grid", Required: true},
+			{Name: "name", Description: "New name of the channel", Required: true},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			channelID, err := resolveChannelID(ctx, s, args["channel_id"])
+			if err != nil {
+				return nil, err
+			}
+
+			if s.enterpriseID != "" {
+				_, err := s.enterpriseClient.RenameConversation(ctx, channelID, args["name"])
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{
+					"channel_id":   channelID,
+					"channel_name": args["name"],
+				}, nil
+			}
+
+			channel, err := s.client.RenameConversationContext(ctx, channelID, args["name"])
+			if err != nil {
+				return nil, fmt.Errorf("baton-slack: error renaming channel: %w", pkg.WrapTokenError(err))
+			}
+			return channelOutput(channel), nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "archive_channel",
+		Description: "Archive a channel. On Enterprise Grid the admin API is used, so the bot doesn't need to be a member of the channel",
+		Arguments: []ActionArgument{
+			{Name: "channel_id", Description: "ID of the channel, or its name on Enterprise Grid", Required: true},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			channelID, err := resolveChannelID(ctx, s, args["channel_id"])
+			if err != nil {
+				return nil, err
+			}
+
+			if s.enterpriseID != "" {
+				_, err = s.enterpriseClient.ArchiveConversation(ctx, channelID)
+			} else {
+				err = s.client.ArchiveConversationContext(ctx, channelID)
+				if err != nil {
+					err = fmt.Errorf("baton-slack: error archiving channel: %w", pkg.WrapTokenError(err))
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"channel_id": channelID,
+				"archived":   true,
+			}, nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "create_channel",
+		Description: "Create a public or private channel. On Enterprise Grid the admin API is used, and the channel is organization-wide unless a workspace is given",
+		Arguments: []ActionArgument{
+			{Name: "name", Description: "Name of the channel", Required: true},
+			{Name: "is_private", Description: "true to create a private channel"},
+			{Name: "team_id", Description: "ID of the workspace the channel belongs to"},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			var isPrivate bool
+			if args["is_private"] != "" {
+				var err error
+				isPrivate, err = strconv.ParseBool(args["is_private"])
+				if err != nil {
+					return nil, fmt.Errorf("baton-slack: invalid is_private: %w", err)
+				}
+			}
+
+			if s.enterpriseID != "" {
+				channelID, _, err := s.enterpriseClient.CreateConversation(ctx, args["name"], isPrivate, args["team_id"])
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{
+					"channel_id":   channelID,
+					"channel_name": args["name"],
+					"is_private":   isPrivate,
+				}, nil
+			}
+
+			channel, err := s.client.CreateConversationContext(ctx, slack.CreateConversationParams{
+				ChannelName: args["name"],
+				IsPrivate:   isPrivate,
+				TeamID:      args["team_id"],
+			})
+			if err != nil {
+				return nil, fmt.Errorf("baton-slack: error creating channel: %w", pkg.WrapTokenError(err))
+			}
+			output := channelOutput(channel)
+			output["is_private"] = channel.IsPrivate
+			return output, nil
+		},
+	})
+}
+
+// channelIDPattern matches the IDs of public and private channels.
+var channelIDPattern = regexp.MustCompile(`^[CG][A-Z0-9]{6,}$`)
+
+// resolveChannelID returns the ID of the channel with the given ID or name.
+// Names can only be resolved across the organization on Enterprise Grid.
+func resolveChannelID(ctx context.Context, s *Slack, idOrName string) (string, error) {
+	if channelIDPattern.MatchString(idOrName) {
+		return idOrName, nil
+	}
+	if s.enterpriseID == "" {
+		return "", fmt.Errorf("baton-slack: %q is not a channel ID, names can only be resolved on Enterprise Grid", idOrName)
+	}
+
+	channel, _, err := s.enterpriseClient.LookupConversationByName(ctx, idOrName)
+	if err != nil {
+		return "", err
+	}
+	return channel.ID, nil
+}
+
+func channelOutput(channel *slack.Channel) map[string]interface{} {
+	return map[string]interface{}{
+		"channel_id":   channel.ID,
+		"channel_name": channel.Name,
+		"topic":        channel.Topic.Value,
+		"purpose":      channel.Purpose.Value,
+	}
+}
@@ -0,0 +1,53 @@
+package connector
+
+import (
+	"context"
+)
+
+func init() {
+	registerAction(sharedInviteAction(
+		"approve_shared_invite",
+		"Approve an inbound Slack Connect invitation",
+		"approved",
+		func(ctx context.Context, s *Slack, inviteID, targetTeam string) error {
+			_, err := s.enterpriseClient.ApproveSharedInvite(ctx, inviteID, targetTeam)
+			return err
+		},
+	))
+	registerAction(sharedInviteAction(
+		"decline_shared_invite",
+		"Decline an inbound Slack Connect invitation",
+		"declined",
+		func(ctx context.Context, s *Slack, inviteID, targetTeam string) error {
+			_, err := s.enterpriseClient.DeclineSharedInvite(ctx, inviteID, targetTeam)
+			return err
+		},
+	))
+}
+
+// sharedInviteAction builds the approve and decline actions, which only differ
+// by the endpoint they call.
+func sharedInviteAction(
+	name string,
+	description string,
+	state string,
+	update func(ctx context.Context, s *Slack, inviteID, targetTeam string) error,
+) *Action {
+	return &Action{
+		Name:        name,
+		Description: description,
+		Arguments: []ActionArgument{
+			{Name: "invite_id", Description: "ID of the Slack Connect invitation", Required: true},
+			{Name: "team_id", Description: "ID of the workspace the channel is shared into, on Enterprise Grid"},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			if err := update(ctx, s, args["invite_id"], args["team_id"]); err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"invite_id": args["invite_id"],
+				"state":     state,
+			}, nil
+		},
+	}
+}
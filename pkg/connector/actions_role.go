@@ -0,0 +1,95 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	registerAction(&Action{
+		Name:        "assign_enterprise_role",
+		Description: "Assign an Enterprise Grid system role to a user, scoped to the organization, a workspace or a channel",
+		Arguments: []ActionArgument{
+			{Name: "role", Description: "ID or name of the system role, e.g. Rl01 or Channel Admin", Required: true},
+			{Name: "user_id", Description: "ID of the user", Required: true},
+			{Name: "entity_id", Description: "ID of the organization, workspace or channel the role is scoped to. Defaults to the organization"},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			if s.enterpriseID == "" {
+				return nil, fmt.Errorf("baton-slack: enterprise roles are only available on Enterprise Grid")
+			}
+
+			roleID, err := lookupSystemRole(args["role"])
+			if err != nil {
+				return nil, err
+			}
+
+			entityID := args["entity_id"]
+			if entityID == "" {
+				entityID = s.enterpriseID
+			}
+
+			_, err = s.enterpriseClient.AddRoleAssignment(ctx, roleID, entityID, args["user_id"])
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"role_id":   roleID,
+				"role_name": systemRoles[roleID],
+				"user_id":   args["user_id"],
+				"entity_id": entityID,
+			}, nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "set_workspace_role",
+		Description: "Make a user an owner, an admin or a regular member of a workspace",
+		Arguments: []ActionArgument{
+			{Name: "team_id", Description: "ID of the workspace", Required: true},
+			{Name: "user_id", Description: "ID of the user", Required: true},
+			{Name: "role", Description: "owner, admin or member", Required: true},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			if s.enterpriseID == "" {
+				return nil, fmt.Errorf("baton-slack: changing workspace roles is only available on Enterprise Grid")
+			}
+
+			// SetWorkspaceRole takes the ID of a workspace role resource, and an
+			// empty one for regular members, like Grant and Revoke do.
+			var roleID string
+			switch args["role"] {
+			case OwnerRoleID, AdminRoleID:
+				roleID = fmt.Sprintf("%s:%s", args["team_id"], args["role"])
+			case MemberRoleID:
+			default:
+				return nil, fmt.Errorf("baton-slack: invalid workspace role %q, expected owner, admin or member", args["role"])
+			}
+
+			_, err := s.enterpriseClient.SetWorkspaceRole(ctx, args["team_id"], args["user_id"], roleID)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"team_id": args["team_id"],
+				"user_id": args["user_id"],
+				"role":    args["role"],
+			}, nil
+		},
+	})
+}
+
+// lookupSystemRole returns the ID of the system role with the given ID or
+// name. Names are matched case-insensitively.
+func lookupSystemRole(role string) (string, error) {
+	if _, ok := systemRoles[role]; ok {
+		return role, nil
+	}
+	for roleID, name := range systemRoles {
+		if strings.EqualFold(name, role) {
+			return roleID, nil
+		}
+	}
+	return "", fmt.Errorf("baton-slack: unknown enterprise role %q", role)
+}
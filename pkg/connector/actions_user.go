@@ -0,0 +1,357 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// notMemberCodes are the errors admin.users.remove reports for a user that
+// isn't in the workspace.
+var notMemberCodes = map[string]bool{
+	"user_not_found":       true,
+	"user_already_deleted": true,
+	"not_a_member":         true,
+}
+
+func init() {
+	registerAction(&Action{
+		Name:        "get_user_info",
+		Description: "Return the live status, workspace roles, 2FA state and workspace memberships of a user",
+		Arguments: []ActionArgument{
+			{Name: "user_id", Description: "ID of the user", Required: true},
+		},
+		ReadOnly: true,
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			user, _, err := s.enterpriseClient.GetUserInfo(ctx, args["user_id"])
+			if err != nil {
+				return nil, err
+			}
+
+			status := "enabled"
+			if user.Deleted {
+				// Slack reports deactivated users as deleted.
+				status = "disabled"
+			}
+
+			// Grid users report every workspace of the organization they belong
+			// to, other users only belong to the workspace of the token.
+			workspaces := user.Enterprise.Teams
+			if len(workspaces) == 0 && user.TeamID != "" {
+				workspaces = []string{user.TeamID}
+			}
+
+			roles := workspaceRoleIDs(*user)
+			if roles == nil {
+				roles = []string{}
+			}
+
+			// Like the synced profile, has_2fa can be false regardless of the
+			// actual state when the token lacks admin rights.
+			return map[string]interface{}{
+				"user_id":         user.ID,
+				"name":            user.Name,
+				"real_name":       user.RealName,
+				"email":           user.Profile.Email,
+				"status":          status,
+				"is_bot":          user.IsBot,
+				"is_stranger":     user.IsStranger,
+				"roles":           roles,
+				"has_2fa":         user.Has2FA,
+				"two_factor_type": user.TwoFactorType,
+				"workspaces":      workspaces,
+				"enterprise_id":   user.Enterprise.EnterpriseID,
+			}, nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "remove_user_from_workspace",
+		Description: "Remove a user from a workspace without deactivating their account",
+		Arguments: []ActionArgument{
+			{Name: "team_id", Description: "ID of the workspace", Required: true},
+			{Name: "user_id", Description: "ID of the user", Required: true},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			if s.enterpriseID == "" {
+				return nil, fmt.Errorf("baton-slack: removing users from a workspace is only available on Enterprise Grid")
+			}
+
+			_, err := s.enterpriseClient.RemoveUserFromTeam(ctx, args["team_id"], args["user_id"])
+			wasMember := true
+			if err != nil {
+				if !notMemberCodes[slackErrorCode(err)] {
+					return nil, err
+				}
+				wasMember = false
+			}
+			return map[string]interface{}{
+				"team_id":    args["team_id"],
+				"user_id":    args["user_id"],
+				"was_member": wasMember,
+			}, nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "update_user_profile",
+		Description: "Update the title and custom profile fields of a user",
+		Arguments: []ActionArgument{
+			{Name: "user_id", Description: "ID of the user", Required: true},
+			{Name: "title", Description: "New title of the user"},
+			{Name: "custom_fields", Description: `JSON object mapping custom profile field IDs to their new value, e.g. {"Xf0123":"Engineering"}`},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			profile := make(map[string]interface{})
+			if title, ok := args["title"]; ok {
+				profile["title"] = title
+			}
+
+			if args["custom_fields"] != "" {
+				var customFields map[string]string
+				if err := json.Unmarshal([]byte(args["custom_fields"]), &customFields); err != nil {
+					return nil, fmt.Errorf("baton-slack: invalid custom_fields: %w", err)
+				}
+				fields := make(map[string]interface{}, len(customFields))
+				for fieldID, value := range customFields {
+					fields[fieldID] = map[string]interface{}{"value": value, "alt": ""}
+				}
+				profile["fields"] = fields
+			}
+
+			if len(profile) == 0 {
+				return nil, fmt.Errorf("baton-slack: update_user_profile requires title or custom_fields")
+			}
+
+			_, err := s.enterpriseClient.SetUserProfile(ctx, args["user_id"], profile)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"user_id": args["user_id"],
+				"updated": profile,
+			}, nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "invite_guest",
+		Description: "Invite a user to a workspace as a guest restricted to the given channels",
+		Arguments: []ActionArgument{
+			{Name: "team_id", Description: "ID of the workspace", Required: true},
+			{Name: "email", Description: "Email of the guest", Required: true},
+			{Name: "channel_ids", Description: "Comma-separated IDs or names of the channels the guest can access", Required: true},
+			{Name: "guest_type", Description: "multi_channel (the default) or single_channel"},
+			{Name: "expires_at", Description: "Unix time at which the guest account is deactivated"},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			if s.enterpriseID == "" {
+				return nil, fmt.Errorf("baton-slack: inviting guests is only available on Enterprise Grid")
+			}
+
+			var channelIDs []string
+			for _, channel := range strings.Split(args["channel_ids"], ",") {
+				if channel = strings.TrimSpace(channel); channel == "" {
+					continue
+				}
+				channelID, err := resolveChannelID(ctx, s, channel)
+				if err != nil {
+					return nil, err
+				}
+				channelIDs = append(channelIDs, channelID)
+			}
+			if len(channelIDs) == 0 {
+				return nil, fmt.Errorf("baton-slack: invite_guest requires at least one channel")
+			}
+
+			var ultraRestricted bool
+			switch args["guest_type"] {
+			case "", "multi_channel":
+			case "single_channel":
+				if len(channelIDs) != 1 {
+					return nil, fmt.Errorf("baton-slack: a single-channel guest must be invited to exactly one channel")
+				}
+				ultraRestricted = true
+			default:
+				return nil, fmt.Errorf("baton-slack: invalid guest type %q, expected multi_channel or single_channel", args["guest_type"])
+			}
+
+			var expiresAt int64
+			if args["expires_at"] != "" {
+				var err error
+				expiresAt, err = strconv.ParseInt(args["expires_at"], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("baton-slack: invalid expires_at: %w", err)
+				}
+			}
+
+			_, err := s.enterpriseClient.InviteGuest(ctx, args["team_id"], args["email"], channelIDs, ultraRestricted, expiresAt)
+			if err != nil {
+				return nil, err
+			}
+
+			roleID := MultiChannelGuestRoleID
+			if ultraRestricted {
+				roleID = SingleChannelGuestRoleID
+			}
+			return map[string]interface{}{
+				"team_id":     args["team_id"],
+				"email":       args["email"],
+				"channel_ids": channelIDs,
+				"role":        roleID,
+			}, nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "update_account",
+		Description: "Write the title, department, manager and display name of a user back to Slack through SCIM",
+		Arguments: []ActionArgument{
+			{Name: "user_id", Description: "ID of the user", Required: true},
+			{Name: "title", Description: "New title of the user"},
+			{Name: "department", Description: "New department of the user"},
+			{Name: "manager", Description: "ID of the new manager of the user"},
+			{Name: "display_name", Description: "New display name of the user"},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			if !s.ssoEnabled {
+				return nil, fmt.Errorf("baton-slack: updating accounts requires the SCIM API, enable it with --sso-enabled")
+			}
+
+			profile := make(map[string]interface{})
+			for _, name := range []string{"title", "department", "manager", "display_name"} {
+				if args[name] != "" {
+					profile[name] = args[name]
+				}
+			}
+			attributes := accountAttributes(profile)
+			if attributes.IsEmpty() {
+				return nil, fmt.Errorf("baton-slack: update_account requires title, department, manager or display_name")
+			}
+
+			_, err := s.enterpriseClient.UpdateUser(ctx, args["user_id"], attributes)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"user_id": args["user_id"],
+				"updated": profile,
+			}, nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "reset_sessions",
+		Description: "Sign a user out of every session, e.g. when their account is compromised",
+		Arguments: []ActionArgument{
+			{Name: "user_id", Description: "ID of the user", Required: true},
+			{Name: "sessions", Description: "all (the default), mobile or web"},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			if s.enterpriseID == "" {
+				return nil, fmt.Errorf("baton-slack: resetting sessions is only available on Enterprise Grid")
+			}
+
+			var mobileOnly, webOnly bool
+			sessions := args["sessions"]
+			switch sessions {
+			case "":
+				sessions = "all"
+			case "all":
+			case "mobile":
+				mobileOnly = true
+			case "web":
+				webOnly = true
+			default:
+				return nil, fmt.Errorf("baton-slack: invalid sessions %q, expected all, mobile or web", sessions)
+			}
+
+			_, err := s.enterpriseClient.ResetUserSessions(ctx, args["user_id"], mobileOnly, webOnly)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"user_id":  args["user_id"],
+				"sessions": sessions,
+			}, nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "clear_user_sessions_and_mfa",
+		Description: "Sign a user out of every session and clear their session settings, e.g. after a lost device",
+		Arguments: []ActionArgument{
+			{Name: "user_id", Description: "ID of the user", Required: true},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			if s.enterpriseID == "" {
+				return nil, fmt.Errorf("baton-slack: clearing sessions is only available on Enterprise Grid")
+			}
+
+			// Sign the user out first: a lost device must not stay signed in if
+			// clearing the settings fails.
+			_, err := s.enterpriseClient.ResetUserSessions(ctx, args["user_id"], false, false)
+			if err != nil {
+				return nil, err
+			}
+			output := map[string]interface{}{
+				"user_id":          args["user_id"],
+				"sessions_reset":   true,
+				"settings_cleared": false,
+			}
+
+			_, err = s.enterpriseClient.ClearUserSessionSettings(ctx, args["user_id"])
+			if err != nil {
+				return output, err
+			}
+			output["settings_cleared"] = true
+			return output, nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "bulk_disable_users",
+		Description: "Deactivate several users through SCIM, reporting the result for each of them",
+		Arguments: []ActionArgument{
+			{Name: "user_ids", Description: "Comma-separated IDs of the users", Required: true},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			if !s.ssoEnabled {
+				return nil, fmt.Errorf("baton-slack: deactivating users requires the SCIM API, enable it with --sso-enabled")
+			}
+
+			var (
+				results []map[string]interface{}
+				failed  int
+			)
+			for _, userID := range strings.Split(args["user_ids"], ",") {
+				userID = strings.TrimSpace(userID)
+				if userID == "" {
+					continue
+				}
+
+				// Keep going on failure: during an incident every account that can
+				// be locked out should be.
+				result := map[string]interface{}{"user_id": userID, "disabled": true}
+				if _, err := s.enterpriseClient.DeactivateUser(ctx, userID); err != nil {
+					result["disabled"] = false
+					result["error"] = err.Error()
+					failed++
+				}
+				results = append(results, result)
+			}
+
+			output := map[string]interface{}{
+				"results":  results,
+				"disabled": len(results) - failed,
+				"failed":   failed,
+			}
+			if failed > 0 {
+				return output, fmt.Errorf("baton-slack: failed to deactivate %d of %d users", failed, len(results))
+			}
+			return output, nil
+		},
+	})
+}
@@ -0,0 +1,112 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/conductorone/baton-slack/pkg"
+	"github.com/slack-go/slack"
+)
+
+func init() {
+	registerAction(&Action{
+		Name:        "add_user_to_user_group",
+		Description: "Add a user to a user group, e.g. an on-call alias, keeping its current members",
+		Arguments: []ActionArgument{
+			{Name: "user_group", Description: "ID or handle of the user group", Required: true},
+			{Name: "user_id", Description: "ID of the user", Required: true},
+			{Name: "team_id", Description: "ID of the workspace the user group belongs to, on Enterprise Grid"},
+		},
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			userGroup, err := findUserGroup(ctx, s.client, args["user_group"], args["team_id"])
+			if err != nil {
+				return nil, err
+			}
+
+			output := map[string]interface{}{
+				"user_group_id": userGroup.ID,
+				"handle":        userGroup.Handle,
+				"user_id":       args["user_id"],
+			}
+
+			// usergroups.users.update replaces the whole member list.
+			if slices.Contains(userGroup.Users, args["user_id"]) {
+				output["already_member"] = true
+				return output, nil
+			}
+
+			members := append(userGroup.Users, args["user_id"])
+			_, err = s.client.UpdateUserGroupMembersContext(ctx, userGroup.ID, strings.Join(members, ","))
+			if err != nil {
+				return nil, fmt.Errorf("baton-slack: error updating user group members: %w", pkg.WrapTokenError(err))
+			}
+			output["already_member"] = false
+			return output, nil
+		},
+	})
+
+	registerAction(&Action{
+		Name:        "lookup_group",
+		Description: "Resolve a user group handle or an IDP group display name to its ID",
+		Arguments: []ActionArgument{
+			{Name: "handle", Description: "Handle of the user group, e.g. @oncall"},
+			{Name: "display_name", Description: "Display name of the IDP group"},
+			{Name: "team_id", Description: "ID of the workspace the user group belongs to, on Enterprise Grid"},
+		},
+		ReadOnly: true,
+		run: func(ctx context.Context, s *Slack, args map[string]string) (map[string]interface{}, error) {
+			switch {
+			case args["handle"] != "":
+				userGroup, _, err := s.enterpriseClient.LookupUserGroupByHandle(ctx, args["team_id"], args["handle"])
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{
+					"user_group_id": userGroup.ID,
+					"handle":        userGroup.Handle,
+					"name":          userGroup.Name,
+				}, nil
+			case args["display_name"] != "":
+				if !s.ssoEnabled {
+					return nil, fmt.Errorf("baton-slack: looking up IDP groups requires the SCIM API, enable it with --sso-enabled")
+				}
+				group, _, err := s.enterpriseClient.LookupIDPGroupByDisplayName(ctx, args["display_name"])
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{
+					"group_id":     group.ID,
+					"display_name": group.DisplayName,
+				}, nil
+			default:
+				return nil, fmt.Errorf("baton-slack: action lookup_group requires either the handle or the display_name argument")
+			}
+		},
+	})
+}
+
+// findUserGroup returns the user group with the given ID or handle, with its
+// members. A leading @ on the handle is ignored.
+func findUserGroup(ctx context.Context, client *slack.Client, idOrHandle string, teamID string) (*slack.UserGroup, error) {
+	options := []slack.GetUserGroupsOption{
+		slack.GetUserGroupsOptionIncludeUsers(true),
+	}
+	if teamID != "" {
+		options = append(options, slack.GetUserGroupsOptionWithTeamID(teamID))
+	}
+
+	userGroups, err := client.GetUserGroupsContext(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("baton-slack: error listing user groups: %w", pkg.WrapTokenError(err))
+	}
+
+	handle := strings.TrimPrefix(idOrHandle, "@")
+	for i, userGroup := range userGroups {
+		if userGroup.ID == idOrHandle || strings.EqualFold(userGroup.Handle, handle) {
+			return &userGroups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("baton-slack: user group %q not found", idOrHandle)
+}
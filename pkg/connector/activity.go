@@ -0,0 +1,92 @@
+package connector
+
+import (
+	"context"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+)
+
+// userActivity is the last known access of the users of a workspace, built
+// from the workspace access logs.
+type userActivity struct {
+	lastAccess map[string]time.Time
+	// complete is set when the logs reach back past the inactivity cutoff. In
+	// that case users without an entry haven't been active since the cutoff.
+	complete bool
+}
+
+// fetchUserActivity walks the access logs of a workspace, most recent first,
+// until it goes past the cutoff or runs out of pages. With full set it reads
+// every page Slack keeps, so that the last login of every user is known.
+func fetchUserActivity(
+	ctx context.Context,
+	client *enterprise.Client,
+	teamID string,
+	cutoff time.Time,
+	full bool,
+) (
+	*userActivity,
+	*v2.RateLimitDescription,
+	error,
+) {
+	activity := &userActivity{lastAccess: make(map[string]time.Time)}
+
+	var ratelimitData *v2.RateLimitDescription
+	for page := 1; page <= enterprise.AccessLogsMaxPage; page++ {
+		logins, paging, rl, err := client.GetAccessLogs(ctx, teamID, page)
+		ratelimitData = rl
+		if err != nil {
+			return nil, ratelimitData, err
+		}
+
+		for _, login := range logins {
+			dateLast := time.Unix(int64(login.DateLast), 0)
+			if dateLast.After(activity.lastAccess[login.UserID]) {
+				activity.lastAccess[login.UserID] = dateLast
+			}
+
+			if dateLast.Before(cutoff) {
+				activity.complete = true
+			}
+		}
+
+		if (activity.complete && !full) || len(logins) == 0 || paging == nil || page >= paging.Pages {
+			break
+		}
+	}
+
+	return activity, ratelimitData, nil
+}
+
+// profile returns the activity attributes of the given user. Users are only
+// flagged when we can tell for sure, otherwise `inactive` is left out. Without
+// inactiveDays, users aren't flagged at all.
+func (a *userActivity) profile(
+	userID string,
+	inactiveDays int,
+	now time.Time,
+) map[string]interface{} {
+	if a == nil {
+		return nil
+	}
+
+	cutoff := now.AddDate(0, 0, -inactiveDays)
+	lastAccess, ok := a.lastAccess[userID]
+	if !ok {
+		if a.complete && inactiveDays > 0 {
+			return map[string]interface{}{"inactive": true}
+		}
+		return nil
+	}
+
+	rv := map[string]interface{}{
+		"last_access": lastAccess.Format(time.RFC3339),
+		"last_login":  lastAccess.Format(time.RFC3339),
+	}
+	if inactiveDays > 0 {
+		rv["inactive"] = lastAccess.Before(cutoff)
+	}
+	return rv
+}
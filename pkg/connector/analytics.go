@@ -0,0 +1,89 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// analyticsDays is how many daily member analytics files are read. Every file
+// lists every member of the organization, so they are large.
+const analyticsDays = 7
+
+// analyticsDateFormat is the date format of admin.analytics.getFile.
+const analyticsDateFormat = "2006-01-02"
+
+// memberActivity is the activity of a user over the analytics window.
+type memberActivity struct {
+	messagesPosted int
+	daysActive     int
+	lastActive     time.Time
+}
+
+// fetchMemberAnalytics reads the member analytics files of the days before
+// now and sums up the activity of every user. Days Slack has no file for yet,
+// or anymore, are skipped.
+func fetchMemberAnalytics(
+	ctx context.Context,
+	client *enterprise.Client,
+	now time.Time,
+) (
+	map[string]*memberActivity,
+	*v2.RateLimitDescription,
+	error,
+) {
+	rv := make(map[string]*memberActivity)
+
+	var ratelimitData *v2.RateLimitDescription
+	for day := 1; day <= analyticsDays; day++ {
+		date := now.UTC().AddDate(0, 0, -day)
+		members, rl, err := client.GetMemberAnalytics(ctx, date.Format(analyticsDateFormat))
+		ratelimitData = rl
+		var apiErr *enterprise.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == enterprise.AnalyticsFileNotFound {
+			ctxzap.Extract(ctx).Debug(
+				"baton-slack: no member analytics for the day",
+				zap.String("date", date.Format(analyticsDateFormat)),
+			)
+			continue
+		}
+		if err != nil {
+			return nil, ratelimitData, err
+		}
+
+		for _, member := range members {
+			activity, ok := rv[member.UserID]
+			if !ok {
+				activity = &memberActivity{}
+				rv[member.UserID] = activity
+			}
+			activity.messagesPosted += member.MessagesPostedCount
+			if !member.IsActive {
+				continue
+			}
+			activity.daysActive++
+			if active, err := time.Parse(analyticsDateFormat, member.Date); err == nil && active.After(activity.lastActive) {
+				activity.lastActive = active
+			}
+		}
+	}
+
+	return rv, ratelimitData, nil
+}
+
+// profile returns the profile attributes of the activity.
+func (a *memberActivity) profile() map[string]interface{} {
+	rv := map[string]interface{}{
+		"messages_posted": a.messagesPosted,
+		"days_active":     a.daysActive,
+	}
+	if !a.lastActive.IsZero() {
+		rv["last_active"] = a.lastActive.Format(analyticsDateFormat)
+	}
+	return rv
+}
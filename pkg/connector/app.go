@@ -0,0 +1,283 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-slack/pkg"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+)
+
+const installedEntitlement = "installed"
+
+// App approval scopes, reported in the grant metadata.
+const (
+	appScopeWorkspace    = "workspace"
+	appScopeOrganization = "organization"
+)
+
+// appResourceType syncs the apps approved or requested in the workspaces of an
+// Enterprise Grid organization. An app is emitted once, and granted to every
+// workspace it is approved for.
+type appResourceType struct {
+	resourceType     *v2.ResourceType
+	enterpriseID     string
+	enterpriseClient *enterprise.Client
+	checkpoint       *checkpointStore
+	// approvals maps the ID of every app seen to the scope of its approval in
+	// each workspace. Apps that are only requested have no approvals.
+	approvals map[string]map[string]string
+	// orgApprovals are the apps approved for the whole organization, fetched
+	// once per builder.
+	orgApprovals []enterprise.ApprovedApp
+	orgFetched   bool
+}
+
+func (o *appResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func appBuilder(
+	enterpriseID string,
+	enterpriseClient *enterprise.Client,
+	checkpoint *checkpointStore,
+) *appResourceType {
+	return &appResourceType{
+		resourceType:     resourceTypeApp,
+		enterpriseID:     enterpriseID,
+		enterpriseClient: enterpriseClient,
+		checkpoint:       checkpoint,
+		approvals:        make(map[string]map[string]string),
+	}
+}
+
+func appResource(
+	appID string,
+	name string,
+	profile map[string]interface{},
+) (*v2.Resource, error) {
+	profile["app_id"] = appID
+	profile["app_name"] = name
+	return resource.NewAppResource(
+		name,
+		resourceTypeApp,
+		appID,
+		[]resource.AppTraitOption{
+			resource.WithAppProfile(profile),
+		},
+	)
+}
+
+// List returns the apps approved or requested in a workspace that haven't
+// been emitted for another workspace yet. Apps approved for the whole
+// organization count as approved in every workspace.
+func (o *appResourceType) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	_ *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	if parentResourceID == nil || o.enterpriseID == "" {
+		return nil, "", nil, nil
+	}
+	teamID := parentResourceID.Resource
+
+	// The checkpoint is the source of truth when enabled, so that apps
+	// emitted before a restart are not emitted again.
+	if o.checkpoint != nil {
+		approvals := make(map[string]map[string]string)
+		if _, err := o.checkpoint.load(checkpointAppApprovals, &approvals); err != nil {
+			return nil, "", nil, err
+		}
+		o.approvals = approvals
+	}
+
+	outputAnnotations := annotations.New()
+	if !o.orgFetched {
+		err := pkg.ForEachPage("approved apps", func(cursor string) (string, error) {
+			apps, nextCursor, ratelimitData, err := o.enterpriseClient.ListApprovedApps(ctx, "", cursor)
+			outputAnnotations.WithRateLimiting(ratelimitData)
+			if err != nil {
+				return "", err
+			}
+			o.orgApprovals = append(o.orgApprovals, apps...)
+			return nextCursor, nil
+		})
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+		o.orgFetched = true
+	}
+
+	var rv []*v2.Resource
+	approve := func(app enterprise.ApprovedApp, scope string) error {
+		_, seen := o.approvals[app.App.ID]
+		if !seen {
+			o.approvals[app.App.ID] = make(map[string]string)
+		}
+		// A workspace approval is more specific than the organization one.
+		if o.approvals[app.App.ID][teamID] != appScopeWorkspace {
+			o.approvals[app.App.ID][teamID] = scope
+		}
+		if seen {
+			return nil
+		}
+
+		r, err := appResource(app.App.ID, app.App.Name, map[string]interface{}{
+			"approved_by":     app.LastResolvedBy.ActorID,
+			"pending_request": false,
+		})
+		if err != nil {
+			return err
+		}
+		rv = append(rv, r)
+		return nil
+	}
+
+	for _, app := range o.orgApprovals {
+		if err := approve(app, appScopeOrganization); err != nil {
+			return nil, "", outputAnnotations, err
+		}
+	}
+
+	err := pkg.ForEachPage("approved apps", func(cursor string) (string, error) {
+		apps, nextCursor, ratelimitData, err := o.enterpriseClient.ListApprovedApps(ctx, teamID, cursor)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return "", err
+		}
+		for _, app := range apps {
+			if err := approve(app, appScopeWorkspace); err != nil {
+				return "", err
+			}
+		}
+		return nextCursor, nil
+	})
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	err = pkg.ForEachPage("app requests", func(cursor string) (string, error) {
+		requests, nextCursor, ratelimitData, err := o.enterpriseClient.ListAppRequests(ctx, teamID, cursor)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return "", err
+		}
+		for _, request := range requests {
+			if _, seen := o.approvals[request.App.ID]; seen {
+				continue
+			}
+			o.approvals[request.App.ID] = make(map[string]string)
+
+			r, err := appResource(request.App.ID, request.App.Name, map[string]interface{}{
+				"pending_request": true,
+				"requested_by":    request.User.ID,
+				"requested_in":    request.Team.ID,
+			})
+			if err != nil {
+				return "", err
+			}
+			rv = append(rv, r)
+		}
+		return nextCursor, nil
+	})
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	if err := o.checkpoint.save(checkpointAppApprovals, o.approvals); err != nil {
+		return nil, "", outputAnnotations, err
+	}
+	return rv, "", outputAnnotations, nil
+}
+
+func (o *appResourceType) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+			entitlement.NewAssignmentEntitlement(
+				resource,
+				installedEntitlement,
+				entitlement.WithGrantableTo(resourceTypeWorkspace),
+				entitlement.WithDescription(
+					fmt.Sprintf(
+						"%s is approved for installation in the workspace",
+						resource.DisplayName,
+					),
+				),
+				entitlement.WithDisplayName(
+					fmt.Sprintf(
+						"%s installed in workspace",
+						resource.DisplayName,
+					),
+				),
+			),
+		},
+		"",
+		nil,
+		nil
+}
+
+// Grants ties an app to the workspaces it is approved for, as recorded while
+// listing the apps of every workspace.
+func (o *appResourceType) Grants(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	if o.checkpoint != nil {
+		approvals := make(map[string]map[string]string)
+		if _, err := o.checkpoint.load(checkpointAppApprovals, &approvals); err != nil {
+			return nil, "", nil, err
+		}
+		o.approvals = approvals
+	}
+
+	scopes := o.approvals[resource.Id.Resource]
+	teamIDs := make([]string, 0, len(scopes))
+	for teamID := range scopes {
+		teamIDs = append(teamIDs, teamID)
+	}
+	sort.Strings(teamIDs)
+
+	rv := make([]*v2.Grant, 0, len(teamIDs))
+	for _, teamID := range teamIDs {
+		workspaceID := &v2.ResourceId{ResourceType: resourceTypeWorkspace.Id, Resource: teamID}
+		rv = append(
+			rv,
+			grant.NewGrant(
+				resource,
+				installedEntitlement,
+				workspaceID,
+				withGrantSourceMetadata(
+					grantSourceAppsApproved,
+					map[string]interface{}{"scope": scopes[teamID]},
+				),
+			),
+		)
+	}
+	return rv, "", nil, nil
+}
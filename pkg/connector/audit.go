@@ -0,0 +1,65 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// auditLog posts a message to a Slack channel for every provisioning operation
+// performed by the connector, giving Slack admins an in-tool audit trail.
+type auditLog struct {
+	client    *slack.Client
+	channelID string
+	// actorID is the Slack user the connector acts as.
+	actorID string
+}
+
+// record posts the outcome of an operation. Failing to post is logged but
+// never fails the operation itself.
+func (a *auditLog) record(
+	ctx context.Context,
+	operation string,
+	principal *v2.ResourceId,
+	target string,
+	opErr error,
+) {
+	if a == nil || a.channelID == "" {
+		return
+	}
+
+	result := ":white_check_mark: succeeded"
+	if opErr != nil {
+		result = fmt.Sprintf(":x: failed: %s", opErr.Error())
+	}
+
+	lines := []string{
+		fmt.Sprintf("*baton-slack %s* %s", operation, result),
+		fmt.Sprintf("• Actor: <@%s>", a.actorID),
+	}
+	if principal != nil {
+		lines = append(lines, fmt.Sprintf("• Principal: `%s:%s`", principal.ResourceType, principal.Resource))
+	}
+	if target != "" {
+		lines = append(lines, fmt.Sprintf("• Target: `%s`", target))
+	}
+
+	_, _, err := a.client.PostMessageContext(
+		ctx,
+		a.channelID,
+		slack.MsgOptionText(strings.Join(lines, "\n"), false),
+	)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn(
+			"baton-slack: failed to post to the audit channel",
+			zap.String("channel_id", a.channelID),
+			zap.String("operation", operation),
+			zap.Error(err),
+		)
+	}
+}
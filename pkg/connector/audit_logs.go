@@ -0,0 +1,184 @@
+package connector
+
+import (
+	"context"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// auditLogActions are the audit log actions listed in the event feed.
+var auditLogActions = []string{
+	"user_login",
+	"channel_created",
+	"role_change_to_admin",
+	"role_change_to_owner",
+	"role_change_to_user",
+	"role_change_to_guest",
+	"app_installed",
+	auditActionAnomaly,
+}
+
+// auditActionAnomaly is the audit log action of the security anomalies Slack
+// detects, such as a session used from another device or excessive downloads.
+const auditActionAnomaly = "anomaly"
+
+// auditLogCursor is the position of the event feed in the audit logs. The
+// audit logs API lists entries newest first, so they are read in windows of
+// time: every page of the current window is listed before the next window
+// starts where it ended.
+type auditLogCursor struct {
+	// Oldest is the start of the current window, as a Unix time.
+	Oldest int64 `json:"oldest,omitempty"`
+	// Latest is the end of the current window, set when its first page is
+	// read.
+	Latest int64 `json:"latest,omitempty"`
+	// Cursor is the next page of the current window.
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// auditLogEvents returns the next page of audit log entries as usage events
+// and advances the cursor. It reports whether the current window has more
+// pages.
+func (s *Slack) auditLogEvents(
+	ctx context.Context,
+	cursor *auditLogCursor,
+	since time.Time,
+) ([]*v2.Event, bool, *v2.RateLimitDescription, error) {
+	now := time.Now()
+	if cursor.Oldest == 0 {
+		cursor.Oldest = now.Unix()
+		if !since.IsZero() {
+			cursor.Oldest = since.Unix()
+		}
+	}
+	if cursor.Latest == 0 {
+		// Entries of the current second may still be written.
+		cursor.Latest = now.Unix() - 1
+		if cursor.Latest < cursor.Oldest {
+			cursor.Latest = 0
+			return nil, false, nil, nil
+		}
+	}
+
+	entries, nextCursor, ratelimitData, err := s.enterpriseClient.GetAuditLogs(
+		ctx,
+		auditLogActions,
+		cursor.Oldest,
+		cursor.Latest,
+		cursor.Cursor,
+	)
+	if err != nil {
+		return nil, false, ratelimitData, err
+	}
+
+	rv := make([]*v2.Event, 0, len(entries))
+	for _, entry := range entries {
+		rv = append(rv, auditLogEvent(entry))
+	}
+
+	if nextCursor != "" {
+		cursor.Cursor = nextCursor
+		return rv, true, ratelimitData, nil
+	}
+	*cursor = auditLogCursor{Oldest: cursor.Latest + 1}
+	return rv, false, ratelimitData, nil
+}
+
+// auditLogEvent converts an audit log entry to a usage event of its actor on
+// its entity. Apps aren't synced, so an app entity is replaced by the
+// workspace it was installed in. Anomalies are flagged as alerts, with the
+// reasons Slack gives for them.
+func auditLogEvent(entry enterprise.AuditEntry) *v2.Event {
+	location := entry.Context.Location
+	fields := map[string]*structpb.Value{
+		"audit_action": structpb.NewStringValue(entry.Action),
+		"ip_address":   structpb.NewStringValue(entry.Context.IPAddress),
+		"user_agent":   structpb.NewStringValue(entry.Context.UA),
+		"location_id":  structpb.NewStringValue(location.ID),
+	}
+
+	var target *v2.Resource
+	switch {
+	case entry.Entity.User != nil:
+		target = auditUserResource(entry.Entity.User)
+	case entry.Entity.Channel != nil:
+		channel := entry.Entity.Channel
+		target = &v2.Resource{
+			Id: &v2.ResourceId{
+				ResourceType: resourceTypeChannel.Id,
+				Resource:     channelResourceID(location.ID, channel.ID),
+			},
+			DisplayName: channel.Name,
+		}
+	case entry.Entity.Workspace != nil:
+		workspace := entry.Entity.Workspace
+		target = &v2.Resource{
+			Id: &v2.ResourceId{
+				ResourceType: resourceTypeWorkspace.Id,
+				Resource:     workspace.ID,
+			},
+			DisplayName: workspace.Name,
+		}
+	case entry.Entity.App != nil:
+		fields["app_id"] = structpb.NewStringValue(entry.Entity.App.ID)
+		fields["app_name"] = structpb.NewStringValue(entry.Entity.App.Name)
+		if location.Type == "workspace" {
+			target = &v2.Resource{
+				Id: &v2.ResourceId{
+					ResourceType: resourceTypeWorkspace.Id,
+					Resource:     location.ID,
+				},
+				DisplayName: location.Name,
+			}
+		}
+	}
+
+	var actor *v2.Resource
+	if entry.Actor.User != nil {
+		actor = auditUserResource(entry.Actor.User)
+	}
+
+	if entry.Action == auditActionAnomaly {
+		reasons := make([]interface{}, 0, len(entry.Details.Reason))
+		for _, reason := range entry.Details.Reason {
+			reasons = append(reasons, reason)
+		}
+		reasonsValue, err := structpb.NewList(reasons)
+		if err == nil {
+			fields["anomaly_reasons"] = structpb.NewListValue(reasonsValue)
+		}
+		fields["alert"] = structpb.NewBoolValue(true)
+		fields["previous_ip_address"] = structpb.NewStringValue(entry.Details.PreviousIPAddress)
+		fields["previous_user_agent"] = structpb.NewStringValue(entry.Details.PreviousUA)
+	}
+
+	annos := annotations.New()
+	annos.Append(&structpb.Struct{Fields: fields})
+
+	return &v2.Event{
+		Id:         "audit:" + entry.ID,
+		OccurredAt: timestamppb.New(time.Unix(entry.DateCreate, 0)),
+		Event: &v2.Event_UsageEvent{
+			UsageEvent: &v2.UsageEvent{
+				TargetResource: target,
+				ActorResource:  actor,
+			},
+		},
+		Annotations: annos,
+	}
+}
+
+func auditUserResource(user *enterprise.AuditUser) *v2.Resource {
+	return &v2.Resource{
+		Id: &v2.ResourceId{
+			ResourceType: resourceTypeUser.Id,
+			Resource:     user.ID,
+		},
+		DisplayName: user.Name,
+	}
+}
@@ -0,0 +1,255 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resources "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-slack/pkg"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// EmailPasswordPolicyID - users assigned to this policy may sign in with an
+// email and password even though the organization enforces SSO.
+const EmailPasswordPolicyID = "email_password"
+
+// authPolicies are the policies admin.auth.policy.getEntities can list. Users
+// that aren't assigned to any of them follow the organization's SSO
+// enforcement.
+var authPolicies = map[string]string{
+	EmailPasswordPolicyID: "Email & password sign in",
+}
+
+type authPolicyType struct {
+	resourceType      *v2.ResourceType
+	enterpriseID      string
+	enterpriseClient  *enterprise.Client
+	verifyBeforeWrite bool
+}
+
+func (o *authPolicyType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func authPolicyBuilder(enterpriseID string, enterpriseClient *enterprise.Client, verifyBeforeWrite bool) *authPolicyType {
+	return &authPolicyType{
+		resourceType:      resourceTypeAuthPolicy,
+		enterpriseID:      enterpriseID,
+		enterpriseClient:  enterpriseClient,
+		verifyBeforeWrite: verifyBeforeWrite,
+	}
+}
+
+func authPolicyResource(
+	_ context.Context,
+	policyName string,
+	_ *v2.ResourceId,
+) (*v2.Resource, error) {
+	displayName, ok := authPolicies[policyName]
+	if !ok {
+		return nil, fmt.Errorf("invalid auth policy: %s", policyName)
+	}
+
+	return resources.NewRoleResource(
+		displayName,
+		resourceTypeAuthPolicy,
+		policyName,
+		[]resources.RoleTraitOption{
+			resources.WithRoleProfile(
+				map[string]interface{}{
+					"policy_name": policyName,
+				},
+			),
+		},
+	)
+}
+
+func (o *authPolicyType) List(
+	ctx context.Context,
+	_ *v2.ResourceId,
+	_ *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	// Authentication policies are only available on enterprise grid.
+	if o.enterpriseID == "" {
+		return nil, "", nil, nil
+	}
+
+	rv := make([]*v2.Resource, 0, len(authPolicies))
+	for policyName := range authPolicies {
+		r, err := authPolicyResource(ctx, policyName, nil)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, r)
+	}
+	return rv, "", nil, nil
+}
+
+func (o *authPolicyType) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+			entitlement.NewAssignmentEntitlement(
+				resource,
+				RoleAssignmentEntitlement,
+				entitlement.WithGrantableTo(resourceTypeUser),
+				entitlement.WithDescription(
+					fmt.Sprintf(
+						"Assigned to the %s authentication policy",
+						resource.DisplayName,
+					),
+				),
+				entitlement.WithDisplayName(
+					fmt.Sprintf(
+						"%s Policy",
+						resource.DisplayName,
+					),
+				),
+			),
+		},
+		"",
+		nil,
+		nil
+}
+
+func (o *authPolicyType) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	pt *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	bag, err := pkg.ParsePageToken(pt.Token, &v2.ResourceId{ResourceType: resourceTypeAuthPolicy.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	outputAnnotations := annotations.New()
+	entities, nextCursor, ratelimitData, err := o.enterpriseClient.GetAuthPolicyEntities(
+		ctx,
+		resource.Id.Resource,
+		bag.PageToken(),
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	pageToken, err := pkg.NextPageToken(bag, "auth policy entities", nextCursor)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	rv := make([]*v2.Grant, 0, len(entities))
+	for _, entity := range entities {
+		userID, err := resources.NewResourceID(resourceTypeUser, entity.EntityID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, grant.NewGrant(resource, RoleAssignmentEntitlement, userID, withGrantSource(grantSourceAuthPolicyEntities)))
+	}
+
+	return rv, pageToken, outputAnnotations, nil
+}
+
+func (o *authPolicyType) Grant(
+	ctx context.Context,
+	principal *v2.Resource,
+	entitlement *v2.Entitlement,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		logger.Warn(
+			"baton-slack: only users can be assigned an auth policy",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: only users can be assigned an auth policy")
+	}
+
+	outputAnnotations := annotations.New()
+	done, err := alreadyInState(
+		ctx,
+		o.verifyBeforeWrite,
+		outputAnnotations,
+		true,
+		hasAuthPolicy(o.enterpriseClient, entitlement.Resource.Id.Resource, principal.Id.Resource),
+	)
+	if err != nil || done {
+		return outputAnnotations, err
+	}
+
+	ratelimitData, err := o.enterpriseClient.AssignAuthPolicy(
+		ctx,
+		entitlement.Resource.Id.Resource,
+		principal.Id.Resource,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	return grantResult(outputAnnotations, err, "failed to assign auth policy")
+}
+
+func (o *authPolicyType) Revoke(
+	ctx context.Context,
+	grant *v2.Grant,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	principal := grant.Principal
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		logger.Warn(
+			"baton-slack: only users can have an auth policy revoked",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: only users can have an auth policy revoked")
+	}
+
+	outputAnnotations := annotations.New()
+	done, err := alreadyInState(
+		ctx,
+		o.verifyBeforeWrite,
+		outputAnnotations,
+		false,
+		hasAuthPolicy(o.enterpriseClient, grant.Entitlement.Resource.Id.Resource, principal.Id.Resource),
+	)
+	if err != nil || done {
+		return outputAnnotations, err
+	}
+
+	ratelimitData, err := o.enterpriseClient.RemoveAuthPolicy(
+		ctx,
+		grant.Entitlement.Resource.Id.Resource,
+		principal.Id.Resource,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	return revokeResult(outputAnnotations, err, "failed to remove auth policy")
+}
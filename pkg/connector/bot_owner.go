@@ -0,0 +1,82 @@
+package connector
+
+import (
+	"context"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-slack/pkg"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+)
+
+// appOwner is who is accountable for an app, as far as Slack records it.
+type appOwner struct {
+	appName string
+	// approvedBy is the ID of the admin who approved the app.
+	approvedBy string
+}
+
+// fetchAppOwners returns the owners of the apps approved for the workspace or
+// for the whole organization, by app ID. Workspace approvals take precedence.
+func fetchAppOwners(
+	ctx context.Context,
+	client *enterprise.Client,
+	teamID string,
+) (
+	map[string]appOwner,
+	*v2.RateLimitDescription,
+	error,
+) {
+	owners := make(map[string]appOwner)
+
+	// The organization is listed first so that workspace approvals override it.
+	scopes := []string{""}
+	if teamID != "" {
+		scopes = append(scopes, teamID)
+	}
+
+	var ratelimitData *v2.RateLimitDescription
+	for _, scope := range scopes {
+		err := pkg.ForEachPage("approved apps", func(cursor string) (string, error) {
+			apps, nextCursor, rl, err := client.ListApprovedApps(ctx, scope, cursor)
+			ratelimitData = rl
+			if err != nil {
+				return "", err
+			}
+
+			for _, app := range apps {
+				owner := appOwner{appName: app.App.Name}
+				if app.LastResolvedBy.ActorType == "user" {
+					owner.approvedBy = app.LastResolvedBy.ActorID
+				}
+				owners[app.App.ID] = owner
+			}
+			return nextCursor, nil
+		})
+		if err != nil {
+			return nil, ratelimitData, err
+		}
+	}
+	return owners, ratelimitData, nil
+}
+
+// botProfile adds the app a bot user belongs to, and who approved it, to the
+// profile of the bot.
+func (u *userOptions) botProfile(profile map[string]interface{}, botID string, appID string) {
+	if botID != "" {
+		profile["bot_id"] = botID
+	}
+	if appID == "" {
+		return
+	}
+	profile["app_id"] = appID
+
+	if u == nil {
+		return
+	}
+	if owner, ok := u.appOwners[appID]; ok {
+		profile["app_name"] = owner.appName
+		if owner.approvedBy != "" {
+			profile["app_approved_by"] = owner.approvedBy
+		}
+	}
+}
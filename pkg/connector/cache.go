@@ -0,0 +1,194 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-slack/pkg"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/slack-go/slack"
+)
+
+// syncCache holds the lookups of a sync, so that they are fetched once per sync
+// instead of once per builder or page. It is shared by all the builders and
+// cleared when a new sync starts. Entries of the LRU caches are evicted once a
+// cache holds size of them.
+type syncCache struct {
+	size int
+
+	// workspaceNames is seeded while listing workspaces and persisted in the
+	// checkpoint.
+	workspaceNames map[string]string
+	// adminUsers holds the pages of admin.users.list by cursor. The whole
+	// organization is listed under every workspace.
+	adminUsers *lruCache[string, adminUsersPage]
+	// idpGroups holds the IDP groups seen while listing them, members
+	// included, so that their grants don't refetch them.
+	idpGroups *lruCache[string, *enterprise.GroupResource]
+	// channelTeams holds the workspaces each channel shared across the
+	// organization is connected to. Those channels are listed by each of
+	// their workspaces, and by both the channel and shared channel builders.
+	channelTeams *lruCache[string, []string]
+	// orgUserGroupsSeen holds the organization-level user groups already
+	// emitted, since they are listed under every workspace they are attached
+	// to. It is persisted in the checkpoint.
+	orgUserGroupsSeen map[string]bool
+
+	// activity holds the access logs of every workspace users are listed for.
+	activity *lruCache[string, *userActivity]
+	// presence holds users.getPresence results since it is one call per user.
+	presence *lruCache[string, string]
+	// emails indexes the emails of the active users synced so far.
+	emails emailIndex
+	// employeeNumbers holds the employee numbers read from SCIM.
+	employeeNumbers map[string]string
+	// analytics holds the member activity read from the analytics files.
+	analytics map[string]*memberActivity
+	// appOwners holds the approved apps of every workspace.
+	appOwners *lruCache[string, map[string]appOwner]
+	// deactivations holds the deactivated users of every workspace.
+	deactivations *lruCache[string, map[string]int]
+}
+
+type adminUsersPage struct {
+	users      []enterprise.UserAdmin
+	nextCursor string
+}
+
+func newSyncCache(size int) *syncCache {
+	c := &syncCache{
+		size:          size,
+		adminUsers:    newLRUCache[string, adminUsersPage](size),
+		idpGroups:     newLRUCache[string, *enterprise.GroupResource](size),
+		channelTeams:  newLRUCache[string, []string](size),
+		activity:      newLRUCache[string, *userActivity](size),
+		presence:      newLRUCache[string, string](size),
+		appOwners:     newLRUCache[string, map[string]appOwner](size),
+		deactivations: newLRUCache[string, map[string]int](size),
+	}
+	c.reset()
+	return c
+}
+
+func (c *syncCache) reset() {
+	c.workspaceNames = make(map[string]string)
+	c.orgUserGroupsSeen = make(map[string]bool)
+	c.adminUsers.purge()
+	c.idpGroups.purge()
+	c.channelTeams.purge()
+	c.activity.purge()
+	c.presence.purge()
+	c.appOwners.purge()
+	c.deactivations.purge()
+	c.emails = make(emailIndex)
+	c.employeeNumbers = nil
+	c.analytics = nil
+}
+
+// channelTeamIDs returns the workspaces the given channel is connected to,
+// sorted, from admin.conversations.getTeams.
+func (c *syncCache) channelTeamIDs(
+	ctx context.Context,
+	client *enterprise.Client,
+	channelID string,
+	outputAnnotations annotations.Annotations,
+) ([]string, error) {
+	if teamIDs, ok := c.channelTeams.get(channelID); ok {
+		return teamIDs, nil
+	}
+
+	var teamIDs []string
+	err := pkg.ForEachPage("channel workspaces", func(cursor string) (string, error) {
+		page, nextCursor, ratelimitData, err := client.GetConversationTeams(ctx, channelID, cursor)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return "", err
+		}
+		teamIDs = append(teamIDs, page...)
+		return nextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(teamIDs)
+	c.channelTeams.add(channelID, teamIDs)
+	return teamIDs, nil
+}
+
+// workspaceName returns the name of the given workspace from the cache,
+// falling back to team.info so that callers don't depend on the order
+// builders run in.
+func (c *syncCache) workspaceName(ctx context.Context, client *slack.Client, teamID string) (string, error) {
+	if name, ok := c.workspaceNames[teamID]; ok {
+		return name, nil
+	}
+
+	teamInfo, err := client.GetOtherTeamInfoContext(ctx, teamID)
+	if err != nil {
+		return "", fmt.Errorf("baton-slack: error fetching workspace %s: %w", teamID, err)
+	}
+
+	c.workspaceNames[teamID] = teamInfo.Name
+	return teamInfo.Name, nil
+}
+
+// isWorkspace reports whether the team is one of the synced workspaces.
+func (c *syncCache) isWorkspace(teamID string) bool {
+	_, ok := c.workspaceNames[teamID]
+	return ok
+}
+
+func (c *syncCache) getAdminUsers(
+	ctx context.Context,
+	client *enterprise.Client,
+	cursor string,
+) (
+	[]enterprise.UserAdmin,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	if page, ok := c.adminUsers.get(cursor); ok {
+		return page.users, page.nextCursor, nil, nil
+	}
+
+	users, nextCursor, ratelimitData, err := client.GetUsersAdmin(ctx, cursor)
+	if err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	c.adminUsers.add(cursor, adminUsersPage{users: users, nextCursor: nextCursor})
+	return users, nextCursor, ratelimitData, nil
+}
+
+func (c *syncCache) setIDPGroups(groups []enterprise.GroupResource) {
+	for i := range groups {
+		c.idpGroups.add(groups[i].ID, &groups[i])
+	}
+}
+
+func (c *syncCache) getIDPGroup(
+	ctx context.Context,
+	client *enterprise.Client,
+	groupID string,
+) (
+	*enterprise.GroupResource,
+	*v2.RateLimitDescription,
+	error,
+) {
+	if group, ok := c.idpGroups.get(groupID); ok {
+		return group, nil, nil
+	}
+
+	group, ratelimitData, err := client.GetIDPGroup(ctx, groupID)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+
+	c.idpGroups.add(groupID, group)
+	return group, ratelimitData, nil
+}
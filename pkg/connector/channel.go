@@ -0,0 +1,659 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resources "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-slack/pkg"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+type channelResourceType struct {
+	resourceType     *v2.ResourceType
+	client           *slack.Client
+	enterpriseID     string
+	enterpriseClient *enterprise.Client
+	cache            *syncCache
+	// channels selects the channels that are synced.
+	channels channelFilter
+	// maxMembersForGrants is the member count above which a channel is
+	// synced without its member grants. Zero syncs the grants of every
+	// channel.
+	maxMembersForGrants int
+	// userGroupGrants grants channels to the user groups they are a default
+	// channel of, see userGroupGrants.
+	userGroupGrants bool
+
+	mu sync.Mutex
+	// defaultChannels holds the user groups of each workspace by default
+	// channel. It is reloaded whenever the channels of the workspace are
+	// listed again.
+	defaultChannels map[string]*defaultChannelGroups
+}
+
+// defaultChannelGroups are the user groups of a workspace by the default
+// channels they add their members to, along with the members of the groups
+// fetched so far.
+type defaultChannelGroups struct {
+	byChannel map[string][]slack.UserGroup
+	members   map[string][]string
+}
+
+func (o *channelResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func channelBuilder(
+	client *slack.Client,
+	enterpriseID string,
+	enterpriseClient *enterprise.Client,
+	cache *syncCache,
+	channels channelFilter,
+	maxMembersForGrants int,
+	userGroupGrants bool,
+) *channelResourceType {
+	return &channelResourceType{
+		resourceType:        resourceTypeChannel,
+		client:              client,
+		enterpriseID:        enterpriseID,
+		enterpriseClient:    enterpriseClient,
+		cache:               cache,
+		channels:            channels,
+		maxMembersForGrants: maxMembersForGrants,
+		userGroupGrants:     userGroupGrants,
+		defaultChannels:     make(map[string]*defaultChannelGroups),
+	}
+}
+
+// skipsGrants reports whether a channel has too many members for its member
+// grants to be synced.
+func (o *channelResourceType) skipsGrants(memberCount int) bool {
+	return o.maxMembersForGrants > 0 && memberCount > o.maxMembersForGrants
+}
+
+// channelResourceID returns the ID of the resource of a channel. Channels
+// shared between workspaces are listed by each of them, so the ID is scoped
+// to the workspace.
+func channelResourceID(teamID string, channelID string) string {
+	return fmt.Sprintf("%s:%s", teamID, channelID)
+}
+
+// channelPlacement is where the resource of a channel goes: the workspace its
+// ID is scoped to, its parent, and the workspaces of the organization it is
+// connected to, if there are several.
+type channelPlacement struct {
+	teamID           string
+	parentResourceID *v2.ResourceId
+	teamIDs          []string
+}
+
+// placeChannel returns the placement of a channel listed by the given
+// workspace. A channel connected to several workspaces of an Enterprise Grid
+// organization is listed by each of them, so it is emitted once, without a
+// parent, under the first of its workspaces that is synced, with all of them
+// in its profile. ok is false if the channel is emitted by another workspace.
+func placeChannel(
+	ctx context.Context,
+	enterpriseID string,
+	enterpriseClient *enterprise.Client,
+	cache *syncCache,
+	channel slack.Channel,
+	parentResourceID *v2.ResourceId,
+	outputAnnotations annotations.Annotations,
+) (channelPlacement, bool, error) {
+	placement := channelPlacement{
+		teamID:           parentResourceID.Resource,
+		parentResourceID: parentResourceID,
+	}
+	if enterpriseID == "" || !channel.IsOrgShared {
+		return placement, true, nil
+	}
+
+	teamIDs, err := cache.channelTeamIDs(ctx, enterpriseClient, channel.ID, outputAnnotations)
+	if err != nil {
+		return placement, false, err
+	}
+	if len(teamIDs) < 2 {
+		return placement, true, nil
+	}
+
+	placement.parentResourceID = nil
+	placement.teamIDs = teamIDs
+	for _, teamID := range teamIDs {
+		if cache.isWorkspace(teamID) {
+			placement.teamID = teamID
+			break
+		}
+	}
+	return placement, placement.teamID == parentResourceID.Resource, nil
+}
+
+// parseChannelResourceID returns the workspace and channel IDs of a channel
+// or shared channel resource.
+func parseChannelResourceID(resourceID string) (string, string, error) {
+	teamID, channelID, ok := strings.Cut(resourceID, ":")
+	if !ok {
+		return "", "", fmt.Errorf("baton-slack: invalid channel ID %q", resourceID)
+	}
+	return teamID, channelID, nil
+}
+
+// Create a new connector resource for a Slack channel. grantsSkipped records
+// that the member grants of the channel aren't synced.
+func channelResource(
+	_ context.Context,
+	channel slack.Channel,
+	placement channelPlacement,
+	grantsSkipped bool,
+) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"channel_id":     channel.ID,
+		"channel_name":   channel.Name,
+		"is_private":     channel.IsPrivate,
+		"is_shared":      channel.IsShared || channel.IsExtShared,
+		"topic":          channel.Topic.Value,
+		"purpose":        channel.Purpose.Value,
+		"member_count":   channel.NumMembers,
+		"grants_skipped": grantsSkipped,
+	}
+	if len(placement.teamIDs) > 0 {
+		profile["team_ids"] = teamIDValues(placement.teamIDs)
+	}
+
+	return resources.NewGroupResource(
+		channel.Name,
+		resourceTypeChannel,
+		channelResourceID(placement.teamID, channel.ID),
+		[]resources.GroupTraitOption{
+			resources.WithGroupProfile(profile),
+		},
+		resources.WithParentResourceID(placement.parentResourceID),
+		resources.WithAnnotation(channelLink(placement.teamID, channel.ID)),
+	)
+}
+
+func teamIDValues(teamIDs []string) []interface{} {
+	rv := make([]interface{}, 0, len(teamIDs))
+	for _, teamID := range teamIDs {
+		rv = append(rv, teamID)
+	}
+	return rv
+}
+
+// List crawls every channel of the workspace with conversations.list, one
+// page per call. Public channels require the channels:read scope, and private
+// channels groups:read; the bot only sees the private channels it is a member
+// of.
+func (o *channelResourceType) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pt *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	bag, err := pkg.ParsePageToken(pt.Token, &v2.ResourceId{ResourceType: resourceTypeChannel.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// The default channels of user groups may have changed since the last
+	// time the workspace was listed.
+	if bag.PageToken() == "" {
+		o.mu.Lock()
+		delete(o.defaultChannels, parentResourceID.Resource)
+		o.mu.Unlock()
+	}
+
+	channels, nextCursor, err := o.client.GetConversationsContext(
+		ctx,
+		&slack.GetConversationsParameters{
+			Cursor:          bag.PageToken(),
+			ExcludeArchived: true,
+			Limit:           conversationsPageSize,
+			Types:           []string{"public_channel", "private_channel"},
+			TeamID:          parentResourceID.Resource,
+		},
+	)
+	if err != nil {
+		annos, err := pkg.AnnotationsForError(err)
+		return nil, "", annos, err
+	}
+
+	pageToken, err := pkg.NextPageToken(bag, "channels", nextCursor)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	outputAnnotations := annotations.New()
+	rv := make([]*v2.Resource, 0, len(channels))
+	for _, channel := range channels {
+		if !o.channels.matches(channel.Name) {
+			continue
+		}
+
+		placement, ok, err := placeChannel(ctx, o.enterpriseID, o.enterpriseClient, o.cache, channel, parentResourceID, outputAnnotations)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+		if !ok {
+			continue
+		}
+
+		r, err := channelResource(ctx, channel, placement, o.skipsGrants(channel.NumMembers))
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, r)
+	}
+
+	return rv, pageToken, outputAnnotations, nil
+}
+
+func (o *channelResourceType) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	grantableTo := []*v2.ResourceType{resourceTypeUser}
+	if o.userGroupGrants {
+		// Channels are granted to the user groups they are a default channel
+		// of.
+		grantableTo = append(grantableTo, resourceTypeUserGroup)
+	}
+
+	return []*v2.Entitlement{
+			entitlement.NewAssignmentEntitlement(
+				resource,
+				memberEntitlement,
+				entitlement.WithGrantableTo(grantableTo...),
+				entitlement.WithDescription(
+					fmt.Sprintf(
+						"Member of the #%s channel",
+						resource.DisplayName,
+					),
+				),
+				entitlement.WithDisplayName(
+					fmt.Sprintf(
+						"#%s Channel %s",
+						resource.DisplayName,
+						memberEntitlement,
+					),
+				),
+			),
+		},
+		"",
+		nil,
+		nil
+}
+
+func (o *channelResourceType) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	pt *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	teamID, channelID, err := parseChannelResourceID(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if o.maxMembersForGrants > 0 {
+		groupTrait, err := resources.GetGroupTrait(resource)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		memberCount, _ := resources.GetProfileInt64Value(groupTrait.GetProfile(), "member_count")
+		if o.skipsGrants(int(memberCount)) {
+			ctxzap.Extract(ctx).Debug(
+				"baton-slack: skipping member grants of large channel",
+				zap.String("channel_id", channelID),
+				zap.Int64("member_count", memberCount),
+			)
+			return nil, "", nil, nil
+		}
+	}
+
+	if o.userGroupGrants {
+		outputAnnotations := annotations.New()
+		userGroups, err := o.defaultChannelUserGroups(ctx, teamID, channelID, outputAnnotations)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+		if len(userGroups) > 0 {
+			return o.userGroupGrantsFor(ctx, resource, teamID, channelID, userGroups, outputAnnotations)
+		}
+	}
+
+	bag, err := pkg.ParsePageToken(pt.Token, &v2.ResourceId{ResourceType: resourceTypeUser.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	members, nextCursor, err := o.client.GetUsersInConversationContext(
+		ctx,
+		&slack.GetUsersInConversationParameters{
+			ChannelID: channelID,
+			Cursor:    bag.PageToken(),
+			Limit:     conversationsPageSize,
+		},
+	)
+	if err != nil {
+		annos, err := pkg.AnnotationsForError(err)
+		return nil, "", annos, err
+	}
+
+	pageToken, err := pkg.NextPageToken(bag, "channel members", nextCursor)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv := make([]*v2.Grant, 0, len(members))
+	for _, member := range members {
+		userID, err := resources.NewResourceID(resourceTypeUser, member)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(
+			rv,
+			grant.NewGrant(resource, memberEntitlement, userID, withGrantSource(grantSourceConversationMembers)),
+		)
+	}
+
+	return rv, pageToken, nil, nil
+}
+
+// userGroupGrantsFor returns the members of a channel that is a default
+// channel of the given user groups. A user group all of whose members are in
+// the channel is granted the channel as a whole, expanded to its members, and
+// those members aren't granted the channel directly, so that reviews show
+// where the access comes from. The remaining members are granted the channel
+// directly. That requires the complete member list, so it is read eagerly.
+func (o *channelResourceType) userGroupGrantsFor(
+	ctx context.Context,
+	resource *v2.Resource,
+	teamID string,
+	channelID string,
+	userGroups []slack.UserGroup,
+	outputAnnotations annotations.Annotations,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	var memberIDs []string
+	err := pkg.ForEachPage("channel members", func(cursor string) (string, error) {
+		members, nextCursor, err := o.client.GetUsersInConversationContext(
+			ctx,
+			&slack.GetUsersInConversationParameters{
+				ChannelID: channelID,
+				Cursor:    cursor,
+				Limit:     conversationsPageSize,
+			},
+		)
+		if err != nil {
+			return "", fmt.Errorf("baton-slack: error listing channel members: %w", pkg.WrapTokenError(err))
+		}
+		memberIDs = append(memberIDs, members...)
+		return nextCursor, nil
+	})
+	if err != nil {
+		annos, err := pkg.AnnotationsForError(err)
+		return nil, "", annos, err
+	}
+
+	inChannel := make(map[string]bool, len(memberIDs))
+	for _, memberID := range memberIDs {
+		inChannel[memberID] = true
+	}
+
+	var rv []*v2.Grant
+	viaUserGroup := make(map[string]bool)
+	for _, userGroup := range userGroups {
+		groupMembers, err := o.userGroupMembers(ctx, teamID, userGroup, outputAnnotations)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+		if len(groupMembers) == 0 || !allIn(groupMembers, inChannel) {
+			continue
+		}
+
+		for _, member := range groupMembers {
+			viaUserGroup[member] = true
+		}
+		userGroupID := &v2.ResourceId{ResourceType: resourceTypeUserGroup.Id, Resource: userGroup.ID}
+		rv = append(
+			rv,
+			grant.NewGrant(
+				resource,
+				memberEntitlement,
+				userGroupID,
+				withGrantSource(grantSourceUserGroupDefaultChannels),
+				grant.WithAnnotation(
+					&v2.GrantExpandable{
+						EntitlementIds: []string{
+							fmt.Sprintf("%s:%s:%s", resourceTypeUserGroup.Id, userGroup.ID, memberEntitlement),
+						},
+					},
+				),
+			),
+		)
+	}
+
+	for _, memberID := range memberIDs {
+		if viaUserGroup[memberID] {
+			continue
+		}
+		userID := &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: memberID}
+		rv = append(
+			rv,
+			grant.NewGrant(resource, memberEntitlement, userID, withGrantSource(grantSourceConversationMembers)),
+		)
+	}
+
+	return rv, "", outputAnnotations, nil
+}
+
+func allIn(ids []string, set map[string]bool) bool {
+	for _, id := range ids {
+		if !set[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultChannelUserGroups returns the user groups of the workspace that have
+// the channel as a default channel. Organization-level user groups list their
+// default channels with admin.usergroups.listChannels.
+func (o *channelResourceType) defaultChannelUserGroups(
+	ctx context.Context,
+	teamID string,
+	channelID string,
+	outputAnnotations annotations.Annotations,
+) ([]slack.UserGroup, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if groups, ok := o.defaultChannels[teamID]; ok {
+		return groups.byChannel[channelID], nil
+	}
+
+	var (
+		userGroups    []slack.UserGroup
+		ratelimitData *v2.RateLimitDescription
+		err           error
+	)
+	if o.enterpriseID != "" {
+		userGroups, ratelimitData, err = o.enterpriseClient.GetUserGroups(ctx, teamID)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+	} else {
+		userGroups, err = o.client.GetUserGroupsContext(ctx)
+		err = pkg.WrapTokenError(err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("baton-slack: error listing user groups: %w", err)
+	}
+
+	groups := &defaultChannelGroups{
+		byChannel: make(map[string][]slack.UserGroup),
+		members:   make(map[string][]string),
+	}
+	for _, userGroup := range userGroups {
+		channelIDs := append(slices.Clone(userGroup.Prefs.Channels), userGroup.Prefs.Groups...)
+		if isOrgUserGroup(userGroup, o.enterpriseID) {
+			channels, ratelimitData, err := o.enterpriseClient.GetUserGroupChannels(ctx, userGroup.ID)
+			outputAnnotations.WithRateLimiting(ratelimitData)
+			if err != nil {
+				return nil, err
+			}
+			for _, channel := range channels {
+				channelIDs = append(channelIDs, channel.ID)
+			}
+		}
+
+		slices.Sort(channelIDs)
+		for _, id := range slices.Compact(channelIDs) {
+			groups.byChannel[id] = append(groups.byChannel[id], userGroup)
+		}
+	}
+	o.defaultChannels[teamID] = groups
+
+	return groups.byChannel[channelID], nil
+}
+
+// userGroupMembers returns the members of a user group of the workspace,
+// fetching them once per listing of the workspace.
+func (o *channelResourceType) userGroupMembers(
+	ctx context.Context,
+	teamID string,
+	userGroup slack.UserGroup,
+	outputAnnotations annotations.Annotations,
+) ([]string, error) {
+	o.mu.Lock()
+	groups, ok := o.defaultChannels[teamID]
+	var members []string
+	if ok {
+		members, ok = groups.members[userGroup.ID]
+	}
+	o.mu.Unlock()
+	if ok {
+		return members, nil
+	}
+
+	var err error
+	if isOrgUserGroup(userGroup, o.enterpriseID) {
+		err = pkg.ForEachPage("user group members", func(cursor string) (string, error) {
+			page, nextCursor, ratelimitData, err := o.enterpriseClient.GetOrgUserGroupMembers(ctx, userGroup.ID, cursor)
+			outputAnnotations.WithRateLimiting(ratelimitData)
+			if err != nil {
+				return "", err
+			}
+			members = append(members, page...)
+			return nextCursor, nil
+		})
+	} else {
+		var ratelimitData *v2.RateLimitDescription
+		members, ratelimitData, err = o.enterpriseClient.GetUserGroupMembers(ctx, userGroup.ID, teamID)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if groups != nil {
+		o.mu.Lock()
+		groups.members[userGroup.ID] = members
+		o.mu.Unlock()
+	}
+	return members, nil
+}
+
+// Grant adds a user to a channel with conversations.invite. The bot has to be
+// a member of private channels, and needs the channels:write.invites or
+// groups:write.invites scope.
+func (o *channelResourceType) Grant(
+	ctx context.Context,
+	principal *v2.Resource,
+	entitlement *v2.Entitlement,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		logger.Warn(
+			"baton-slack: only users can be added to a channel",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: only users can be added to a channel")
+	}
+
+	_, channelID, err := parseChannelResourceID(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = o.client.InviteUsersToConversationContext(ctx, channelID, principal.Id.Resource)
+	return grantResult(annotations.New(), pkg.WrapTokenError(err), "failed to add user to channel")
+}
+
+// Revoke removes a user from a channel with conversations.kick, which needs
+// the channels:manage or groups:write scope.
+func (o *channelResourceType) Revoke(
+	ctx context.Context,
+	grant *v2.Grant,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	principal := grant.Principal
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		logger.Warn(
+			"baton-slack: only users can be removed from a channel",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: only users can be removed from a channel")
+	}
+
+	_, channelID, err := parseChannelResourceID(grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.client.KickUserFromConversationContext(ctx, channelID, principal.Id.Resource)
+	return revokeResult(annotations.New(), pkg.WrapTokenError(err), "failed to remove user from channel")
+}
@@ -0,0 +1,30 @@
+package connector
+
+import (
+	"regexp"
+)
+
+// channelFilter selects channels by name. A channel is kept if it matches any
+// include pattern, or if there are none, and doesn't match any exclude
+// pattern.
+type channelFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+func (f channelFilter) matches(name string) bool {
+	for _, re := range f.exclude {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package connector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint keys, one per builder that keeps progress in memory.
+const (
+	checkpointWorkspaceNames    = "workspace_names"
+	checkpointOrgUserGroupsSeen = "org_user_groups_seen"
+	checkpointAppApprovals      = "app_approvals"
+)
+
+// checkpointStore persists the progress builders keep in memory between
+// pages. The SDK already checkpoints page tokens and resumes an interrupted
+// sync from them, but without this state a resumed sync would e.g. fail to
+// name workspace roles because the workspaces were listed before the restart.
+// A nil store persists nothing.
+type checkpointStore struct {
+	path string
+
+	mu    sync.Mutex
+	state map[string]json.RawMessage
+}
+
+// loadCheckpointStore reads the checkpoint at the given path, if any. An empty
+// path disables checkpointing.
+func loadCheckpointStore(path string) (*checkpointStore, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	c := &checkpointStore{
+		path:  path,
+		state: make(map[string]json.RawMessage),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("baton-slack: error reading checkpoint: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return nil, fmt.Errorf("baton-slack: error parsing checkpoint: %w", err)
+	}
+	return c, nil
+}
+
+// load decodes the progress stored under the given key into target. It
+// reports whether anything was stored.
+func (c *checkpointStore) load(key string, target interface{}) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.state[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return false, fmt.Errorf("baton-slack: error parsing checkpoint %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// save stores the progress under the given key and writes the checkpoint.
+func (c *checkpointStore) save(key string, value interface{}) error {
+	if c == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("baton-slack: error encoding checkpoint %s: %w", key, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state[key] = data
+	return c.write()
+}
+
+// reset drops all progress. It is called when a new sync starts.
+func (c *checkpointStore) reset() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state = make(map[string]json.RawMessage)
+	return c.write()
+}
+
+// write replaces the checkpoint file atomically so that a crash mid-write
+// can't leave a corrupt checkpoint behind.
+func (c *checkpointStore) write() error {
+	data, err := json.Marshal(c.state)
+	if err != nil {
+		return fmt.Errorf("baton-slack: error encoding checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("baton-slack: error writing checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("baton-slack: error writing checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("baton-slack: error writing checkpoint: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("baton-slack: error writing checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package connector
+
+import (
+	"encoding/json"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-slack/pkg"
+)
+
+// maxGrantsPerPage bounds the size of a grants page built from a page of
+// users. A single user can produce several grants, so a full users.list page
+// would otherwise make for very large grant pages.
+const maxGrantsPerPage = 500
+
+// chunkedPage is the position within a page of users: the cursor the page was
+// fetched with and the first user that hasn't been emitted yet.
+type chunkedPage struct {
+	Cursor string `json:"cursor"`
+	Offset int    `json:"offset,omitempty"`
+}
+
+func parseChunkedPageToken(token string) (*pagination.Bag, *chunkedPage, error) {
+	bag, err := pkg.ParsePageToken(token, &v2.ResourceId{ResourceType: resourceTypeUser.Id})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := &chunkedPage{}
+	if bag.PageToken() != "" {
+		// Tokens checkpointed by older versions hold the bare Slack cursor.
+		if err := json.Unmarshal([]byte(bag.PageToken()), page); err != nil {
+			page.Cursor = bag.PageToken()
+		}
+	}
+	return bag, page, nil
+}
+
+// next returns the token for the rest of the current page of users if
+// nextOffset is set, otherwise for the next page.
+func (p *chunkedPage) next(bag *pagination.Bag, nextCursor string, nextOffset int) (string, error) {
+	if err := pkg.CheckCursor("users", p.Cursor, nextCursor); err != nil {
+		return "", err
+	}
+
+	var next *chunkedPage
+	switch {
+	case nextOffset > 0:
+		next = &chunkedPage{Cursor: p.Cursor, Offset: nextOffset}
+	case nextCursor != "":
+		next = &chunkedPage{Cursor: nextCursor}
+	default:
+		return bag.NextToken("")
+	}
+
+	token, err := json.Marshal(next)
+	if err != nil {
+		return "", err
+	}
+	return bag.NextToken(string(token))
+}
+
+// chunkGrants appends the grants of the users starting at offset until
+// maxGrantsPerPage is reached, always making progress by at least one user.
+// It returns the offset of the first user left out, or 0 if every user was
+// processed.
+func chunkGrants[T any](
+	rv []*v2.Grant,
+	users []T,
+	offset int,
+	toGrants func(T) ([]*v2.Grant, error),
+) ([]*v2.Grant, int, error) {
+	for i := offset; i < len(users); i++ {
+		if len(rv) >= maxGrantsPerPage && i > offset {
+			return rv, i, nil
+		}
+
+		grants, err := toGrants(users[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		rv = append(rv, grants...)
+	}
+	return rv, 0, nil
+}
+
+// cachedPage keeps the last page fetched so that emitting it in chunks doesn't
+// refetch it for every chunk.
+type cachedPage[T any] struct {
+	key        string
+	items      []T
+	nextCursor string
+}
+
+func (c *cachedPage[T]) get(
+	parentID string,
+	cursor string,
+	fetch func() ([]T, string, error),
+) ([]T, string, error) {
+	key := parentID + "/" + cursor
+	if c.key == key {
+		return c.items, c.nextCursor, nil
+	}
+
+	items, nextCursor, err := fetch()
+	if err != nil {
+		return nil, "", err
+	}
+
+	c.key = key
+	c.items = items
+	c.nextCursor = nextCursor
+	return items, nextCursor, nil
+}
@@ -0,0 +1,140 @@
+package enterprise
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/conductorone/baton-slack/pkg"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// AnalyticsFileNotFound is the error code of admin.analytics.getFile when
+// there is no file for the date, either because it is too recent or because
+// it is past the retention period.
+const AnalyticsFileNotFound = "file_not_found"
+
+// maxAnalyticsLineSize bounds a line of the analytics file, one member each.
+const maxAnalyticsLineSize = 1 << 20
+
+// MemberAnalytics is the activity of a member on a given day, as reported by
+// the member analytics file.
+type MemberAnalytics struct {
+	Date                       string `json:"date"`
+	EnterpriseID               string `json:"enterprise_id"`
+	TeamID                     string `json:"team_id"`
+	UserID                     string `json:"user_id"`
+	IsGuest                    bool   `json:"is_guest"`
+	IsBillableSeat             bool   `json:"is_billable_seat"`
+	IsActive                   bool   `json:"is_active"`
+	MessagesPostedCount        int    `json:"messages_posted_count"`
+	ChannelMessagesPostedCount int    `json:"channel_messages_posted_count"`
+	ReactionsAddedCount        int    `json:"reactions_added_count"`
+	FilesAddedCount            int    `json:"files_added_count"`
+}
+
+// GetMemberAnalytics downloads the member analytics file of the given day,
+// formatted as YYYY-MM-DD, and returns one entry per member. The file is a
+// gzipped list of JSON objects, one per line, and is large for big
+// organizations. It requires the admin.analytics:read scope on the enterprise
+// token.
+func (c *Client) GetMemberAnalytics(
+	ctx context.Context,
+	date string,
+) (
+	[]MemberAnalytics,
+	*v2.RateLimitDescription,
+	error,
+) {
+	if err := c.tokenError(c.token); err != nil {
+		return nil, nil, err
+	}
+
+	logger := ctxzap.Extract(ctx)
+	output := c.getUrl(UrlPathAnalyticsGetFile, nil, false)
+	logger.Debug(
+		"making request",
+		zap.String("method", http.MethodPost),
+		zap.String("url", output.String()),
+	)
+
+	request, err := c.wrapper.NewRequest(
+		ctx,
+		http.MethodPost,
+		output,
+		WithBearerToken(c.token),
+		uhttp.WithFormBody(toValues(map[string]interface{}{
+			"type": "member",
+			"date": date,
+		})),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ratelimitData v2.RateLimitDescription
+	response, err := c.wrapper.Do(
+		request,
+		uhttp.WithRatelimitData(&ratelimitData),
+	)
+	if err != nil {
+		return nil, &ratelimitData, fmt.Errorf("baton-slack: error fetching member analytics: %w", err)
+	}
+	defer response.Body.Close()
+
+	// The file is only sent on success, errors come as a regular JSON body.
+	body := bufio.NewReader(response.Body)
+	magic, err := body.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, &ratelimitData, err
+	}
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		var baseResponse BaseResponse
+		if err := json.NewDecoder(body).Decode(&baseResponse); err != nil {
+			return nil, &ratelimitData, fmt.Errorf("baton-slack: error fetching member analytics: %w", err)
+		}
+		if tokenErr := pkg.NewTokenHealthError(baseResponse.Error); tokenErr != nil {
+			c.setTokenError(c.token, tokenErr)
+			return nil, &ratelimitData, tokenErr
+		}
+		if err := baseResponse.handleError(nil, "fetching member analytics"); err != nil {
+			return nil, &ratelimitData, err
+		}
+		return nil, &ratelimitData, nil
+	}
+
+	reader, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, &ratelimitData, err
+	}
+	defer reader.Close()
+
+	var rv []MemberAnalytics
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxAnalyticsLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var member MemberAnalytics
+		if err := json.Unmarshal(line, &member); err != nil {
+			return nil, &ratelimitData, fmt.Errorf("baton-slack: error parsing member analytics: %w", err)
+		}
+		rv = append(rv, member)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &ratelimitData, fmt.Errorf("baton-slack: error reading member analytics: %w", err)
+	}
+
+	return rv, &ratelimitData, nil
+}
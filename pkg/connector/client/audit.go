@@ -0,0 +1,143 @@
+package enterprise
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-slack/pkg"
+)
+
+// AuditLogsPageSize is how many entries are requested per audit logs page.
+const AuditLogsPageSize = 200
+
+// AuditUser is a user as the audit logs API reports it.
+type AuditUser struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Team  string `json:"team"`
+}
+
+// AuditEntity is the actor or the target of an audit log entry. Type tells
+// which of the fields is set.
+type AuditEntity struct {
+	Type    string     `json:"type"`
+	User    *AuditUser `json:"user,omitempty"`
+	Channel *struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Privacy     string `json:"privacy"`
+		IsShared    bool   `json:"is_shared"`
+		IsOrgShared bool   `json:"is_org_shared"`
+	} `json:"channel,omitempty"`
+	App *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"app,omitempty"`
+	Workspace *struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Domain string `json:"domain"`
+	} `json:"workspace,omitempty"`
+}
+
+// AuditDetails holds the action specific fields of an audit log entry. Only
+// the fields of anomaly entries are read.
+type AuditDetails struct {
+	// Reason lists why Slack flagged an anomaly, e.g. session_fingerprint,
+	// excessive_downloads or a change of ip_address or asn.
+	Reason            []string `json:"reason,omitempty"`
+	PreviousIPAddress string   `json:"previous_ip_address,omitempty"`
+	PreviousUA        string   `json:"previous_ua,omitempty"`
+}
+
+// AuditEntry is an entry of the audit logs.
+type AuditEntry struct {
+	ID         string       `json:"id"`
+	DateCreate int64        `json:"date_create"`
+	Action     string       `json:"action"`
+	Actor      AuditEntity  `json:"actor"`
+	Entity     AuditEntity  `json:"entity"`
+	Details    AuditDetails `json:"details"`
+	Context    struct {
+		Location struct {
+			Type   string `json:"type"`
+			ID     string `json:"id"`
+			Name   string `json:"name"`
+			Domain string `json:"domain"`
+		} `json:"location"`
+		UA        string `json:"ua"`
+		IPAddress string `json:"ip_address"`
+	} `json:"context"`
+}
+
+// GetAuditLogs returns a page of the audit log entries with the given actions
+// created between oldest and latest, as Unix times, newest first. A zero bound
+// is left out. It requires the auditlogs:read scope, which only an org owner
+// can grant, on the enterprise token.
+func (c *Client) GetAuditLogs(
+	ctx context.Context,
+	actions []string,
+	oldest int64,
+	latest int64,
+	cursor string,
+) (
+	[]AuditEntry,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	if err := c.tokenError(c.token); err != nil {
+		return nil, "", nil, err
+	}
+
+	values := map[string]interface{}{
+		"limit": AuditLogsPageSize,
+	}
+	if len(actions) > 0 {
+		values["action"] = strings.Join(actions, ",")
+	}
+	if oldest > 0 {
+		values["oldest"] = int(oldest)
+	}
+	if latest > 0 {
+		values["latest"] = int(latest)
+	}
+	if cursor != "" {
+		values["cursor"] = cursor
+	}
+
+	output := c.baseAuditUrl.JoinPath(UrlPathAuditLogs)
+	output.RawQuery = toValues(values)
+
+	var response struct {
+		BaseResponse
+		Entries []AuditEntry `json:"entries"`
+		Pagination
+	}
+
+	ratelimitData, err := c.doRequest(
+		ctx,
+		http.MethodGet,
+		output,
+		&response,
+		WithBearerToken(c.token),
+	)
+
+	var tokenErr *pkg.TokenHealthError
+	if errors.As(err, &tokenErr) {
+		c.setTokenError(c.token, tokenErr)
+	}
+
+	if err := response.handleError(err, "fetching audit logs"); err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	return response.Entries,
+		response.ResponseMetadata.NextCursor,
+		ratelimitData,
+		nil
+}
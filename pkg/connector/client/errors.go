@@ -0,0 +1,192 @@
+package enterprise
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIError is an error reported by the Slack Web API in the response body.
+type APIError struct {
+	Action   string
+	Code     string
+	Needed   string
+	Provided string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf(
+		"baton-slack: error %s: error %v needed %v provided %v",
+		e.Action,
+		e.Code,
+		e.Needed,
+		e.Provided,
+	)
+}
+
+// SCIMError is the error body returned by the SCIM API, see
+// https://datatracker.ietf.org/doc/html/rfc7644#section-3.12
+type SCIMError struct {
+	Schemas []string `json:"schemas"`
+	Detail  string   `json:"detail"`
+	// Status is the HTTP status code. The RFC defines it as a string, but it is
+	// sometimes sent as a number.
+	Status json.Number `json:"status"`
+}
+
+func (e *SCIMError) Error() string {
+	return fmt.Sprintf("baton-slack: SCIM request failed with status %s: %s", e.Status, e.Detail)
+}
+
+func (e *SCIMError) StatusCode() int {
+	statusCode, err := strconv.Atoi(e.Status.String())
+	if err != nil {
+		return 0
+	}
+	return statusCode
+}
+
+// GRPCStatus maps the SCIM status to a gRPC status so that the SDK can tell
+// retryable failures apart from permanent ones.
+func (e *SCIMError) GRPCStatus() *status.Status {
+	return status.New(scimStatusToCode(e.StatusCode()), e.Error())
+}
+
+func scimStatusToCode(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge:
+		return codes.ResourceExhausted
+	case http.StatusTooManyRequests:
+		return codes.Unavailable
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	}
+
+	if statusCode >= 500 && statusCode <= 599 {
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
+
+// RateLimitError is returned when Slack kept rate limiting a request after it
+// was retried as many times as allowed. It wraps the error of the last
+// attempt.
+type RateLimitError struct {
+	// RetryAfter is how long Slack asked to wait before the next attempt.
+	RetryAfter time.Duration
+	Attempts   int
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf(
+		"baton-slack: rate limited after %d attempts, retry after %s: %v",
+		e.Attempts,
+		e.RetryAfter,
+		e.Err,
+	)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus reports rate limits as Unavailable so that the SDK retries the
+// operation later.
+func (e *RateLimitError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
+
+// retryAfter returns the wait Slack asks for in the Retry-After header of a
+// response, given either in seconds or as an HTTP date, or zero if it is
+// missing or malformed.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+type scimV1Error struct {
+	Errors struct {
+		Description string `json:"description"`
+		Code        int    `json:"code"`
+	} `json:"Errors"`
+}
+
+// parseSCIMError returns the SCIM error from a failed response or nil if the
+// body doesn't contain one.
+func parseSCIMError(response *http.Response) *SCIMError {
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil || len(bodyBytes) == 0 {
+		return nil
+	}
+
+	var scimErr SCIMError
+	if err := json.Unmarshal(bodyBytes, &scimErr); err != nil {
+		return nil
+	}
+
+	// SCIM v1 nests the error under Errors.
+	if scimErr.Detail == "" && scimErr.Status == "" {
+		var v1Err scimV1Error
+		if err := json.Unmarshal(bodyBytes, &v1Err); err == nil && v1Err.Errors.Description != "" {
+			scimErr.Detail = v1Err.Errors.Description
+			if v1Err.Errors.Code != 0 {
+				scimErr.Status = json.Number(strconv.Itoa(v1Err.Errors.Code))
+			}
+		}
+	}
+
+	if scimErr.Detail == "" && scimErr.Status == "" {
+		return nil
+	}
+
+	// Not every error body includes the status, so fall back to the HTTP one.
+	if scimErr.Status == "" {
+		scimErr.Status = json.Number(strconv.Itoa(response.StatusCode))
+	}
+
+	return &scimErr
+}
+
+// isSCIMConflict reports whether the error means that the resource was changed
+// by someone else in the meantime.
+func isSCIMConflict(err error) bool {
+	var scimErr *SCIMError
+	if !errors.As(err, &scimErr) {
+		return false
+	}
+
+	statusCode := scimErr.StatusCode()
+	return statusCode == http.StatusConflict || statusCode == http.StatusPreconditionFailed
+}
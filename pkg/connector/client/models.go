@@ -43,6 +43,68 @@ type UserAdmin struct {
 	HasSso            bool     `json:"has_sso"`
 }
 
+// AdminConversation is a channel as returned by admin.conversations.search.
+type AdminConversation struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Purpose          string   `json:"purpose"`
+	MemberCount      int      `json:"member_count"`
+	Created          int      `json:"created"`
+	IsPrivate        bool     `json:"is_private"`
+	IsArchived       bool     `json:"is_archived"`
+	IsExtShared      bool     `json:"is_ext_shared"`
+	IsOrgShared      bool     `json:"is_org_shared"`
+	InternalTeamIDs  []string `json:"internal_team_ids"`
+	ConnectedTeamIDs []string `json:"connected_team_ids"`
+}
+
+// ApprovedApp is an app approval as returned by admin.apps.approved.list.
+type ApprovedApp struct {
+	App struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"app"`
+	// LastResolvedBy is the admin who approved the app.
+	LastResolvedBy struct {
+		ActorID   string `json:"actor_id"`
+		ActorType string `json:"actor_type"`
+	} `json:"last_resolved_by"`
+	DateUpdated int `json:"date_updated"`
+}
+
+// AppRequest is a request to install an app as returned by
+// admin.apps.requests.list.
+type AppRequest struct {
+	ID  string `json:"id"`
+	App struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"app"`
+	User struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	} `json:"user"`
+	Team struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"team"`
+	DateCreated int `json:"date_created"`
+}
+
+type AuthPolicyEntity struct {
+	EntityID   string `json:"entity_id"`
+	EntityType string `json:"entity_type"`
+	DateAdded  int    `json:"date_added"`
+}
+
+type UserGroupChannel struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	TeamID     string `json:"team_id"`
+	NumMembers int    `json:"num_members"`
+}
+
 type RoleAssignment struct {
 	RoleID     string `json:"role_id"`
 	EntityID   string `json:"entity_id"`
@@ -173,6 +235,42 @@ type GroupResource struct {
 	Members     []Member `json:"members"`
 }
 
+// SCIMNewUser is the body of a SCIM user creation.
+type SCIMNewUser struct {
+	Schemas     []string `json:"schemas"`
+	UserName    string   `json:"userName"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Name        *Name    `json:"name,omitempty"`
+	Emails      []Email  `json:"emails"`
+	Active      bool     `json:"active"`
+}
+
+// SCIMUser holds the attributes we read from SCIM users. The enterprise
+// extension is keyed by a different schema URN in v1 and v2.
+type SCIMUser struct {
+	ID           string                   `json:"id"`
+	UserName     string                   `json:"userName"`
+	Active       bool                     `json:"active"`
+	Enterprise   *SCIMEnterpriseExtension `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	EnterpriseV1 *SCIMEnterpriseExtension `json:"urn:scim:schemas:extension:enterprise:1.0,omitempty"`
+}
+
+type SCIMEnterpriseExtension struct {
+	EmployeeNumber string `json:"employeeNumber"`
+}
+
+// EmployeeNumber returns the employee number from whichever version of the
+// enterprise extension is present.
+func (u SCIMUser) EmployeeNumber() string {
+	if u.Enterprise != nil && u.Enterprise.EmployeeNumber != "" {
+		return u.Enterprise.EmployeeNumber
+	}
+	if u.EnterpriseV1 != nil {
+		return u.EnterpriseV1.EmployeeNumber
+	}
+	return ""
+}
+
 type PatchOp struct {
 	Schemas    []string      `json:"schemas"`
 	Operations []ScimOperate `json:"Operations"`
@@ -181,7 +279,7 @@ type PatchOp struct {
 type ScimOperate struct {
 	Op    string   `json:"op"`
 	Path  string   `json:"path"`
-	Value []UserID `json:"value"`
+	Value []UserID `json:"value,omitempty"`
 }
 
 type UserID struct {
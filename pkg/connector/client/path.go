@@ -7,20 +7,57 @@ import (
 )
 
 const (
-	UrlPathGetRoleAssignments  = "/api/admin.roles.listAssignments"
-	UrlPathGetTeams            = "/api/admin.teams.list"
-	UrlPathGetUserGroupMembers = "/api/usergroups.users.list"
-	UrlPathGetUserGroups       = "/api/usergroups.list"
-	UrlPathGetUserInfo         = "/api/users.info"
-	UrlPathGetUsers            = "/api/users.list"
-	UrlPathGetUsersAdmin       = "/api/admin.users.list"
-	UrlPathIDPGroup            = "/scim/v2/Groups/%s"
-	UrlPathIDPGroups           = "/scim/v2/Groups"
-	UrlPathSetAdmin            = "/api/admin.users.setAdmin"
-	UrlPathSetOwner            = "/api/admin.users.setOwner"
-	UrlPathSetRegular          = "/api/admin.users.setRegular"
-	baseScimUrl                = "https://api.slack.com"
-	baseUrl                    = "https://slack.com"
+	UrlPathAddRoleAssignments     = "/api/admin.roles.addAssignments"
+	UrlPathAnalyticsGetFile       = "/api/admin.analytics.getFile"
+	UrlPathAppApprove             = "/api/admin.apps.approve"
+	UrlPathAppRequests            = "/api/admin.apps.requests.list"
+	UrlPathAppRestrict            = "/api/admin.apps.restrict"
+	UrlPathAppsApproved           = "/api/admin.apps.approved.list"
+	UrlPathAssignUser             = "/api/admin.users.assign"
+	UrlPathAuditLogs              = "/logs"
+	UrlPathAuthPolicyAssign       = "/api/admin.auth.policy.assignEntities"
+	UrlPathAuthPolicyEntities     = "/api/admin.auth.policy.getEntities"
+	UrlPathAuthPolicyRemove       = "/api/admin.auth.policy.removeEntities"
+	UrlPathApproveSharedInvite    = "/api/conversations.approveSharedInvite"
+	UrlPathConversationArchive    = "/api/admin.conversations.archive"
+	UrlPathConversationCreate     = "/api/admin.conversations.create"
+	UrlPathConversationTeams      = "/api/admin.conversations.getTeams"
+	UrlPathConversationSearch     = "/api/admin.conversations.search"
+	UrlPathConversationRename     = "/api/admin.conversations.rename"
+	UrlPathDisconnectShared       = "/api/admin.conversations.disconnectShared"
+	UrlPathDeclineSharedInvite    = "/api/conversations.declineSharedInvite"
+	UrlPathGetAccessLogs          = "/api/team.accessLogs"
+	UrlPathGetRoleAssignments     = "/api/admin.roles.listAssignments"
+	UrlPathGetTeams               = "/api/admin.teams.list"
+	UrlPathGetUserGroupMembers    = "/api/usergroups.users.list"
+	UrlPathGetUserGroups          = "/api/usergroups.list"
+	UrlPathGetUserInfo            = "/api/users.info"
+	UrlPathGetUsers               = "/api/users.list"
+	UrlPathGetUsersAdmin          = "/api/admin.users.list"
+	UrlPathIDPGroup               = "/Groups/%s"
+	UrlPathIDPGroups              = "/Groups"
+	UrlPathIDPUser                = "/Users/%s"
+	UrlPathIDPUsers               = "/Users"
+	UrlPathInviteUser             = "/api/admin.users.invite"
+	UrlPathRemoveRoleAssignments  = "/api/admin.roles.removeAssignments"
+	UrlPathRemoveUser             = "/api/admin.users.remove"
+	UrlPathClearSessionSettings   = "/api/admin.users.session.clearSettings"
+	UrlPathResetSessions          = "/api/admin.users.session.reset"
+	UrlPathSetAdmin               = "/api/admin.users.setAdmin"
+	UrlPathSetOwner               = "/api/admin.users.setOwner"
+	UrlPathSetExpiration          = "/api/admin.users.setExpiration"
+	UrlPathSetRegular             = "/api/admin.users.setRegular"
+	UrlPathSetUserProfile         = "/api/users.profile.set"
+	UrlPathUpdateUserGroupMembers = "/api/usergroups.users.update"
+	UrlPathUserGroupAddTeams      = "/api/admin.usergroups.addTeams"
+	UrlPathUserGroupChannels      = "/api/admin.usergroups.listChannels"
+	UrlPathUserGroupUsers         = "/api/admin.usergroups.listUsers"
+	baseAuditUrl                  = "https://api.slack.com/audit/v1"
+	baseScimUrl                   = "https://api.slack.com"
+	baseUrl                       = "https://slack.com"
+	govBaseAuditUrl               = "https://api.slack-gov.com/audit/v1"
+	govBaseScimUrl                = "https://api.slack-gov.com"
+	govBaseUrl                    = "https://slack-gov.com"
 )
 
 func getWorkspaceUrlPathByRole(roleID string) (string, error) {
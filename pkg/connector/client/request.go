@@ -3,14 +3,17 @@ package enterprise
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/conductorone/baton-slack/pkg"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
 )
@@ -68,7 +71,11 @@ func (c *Client) post(
 		token = c.botToken
 	}
 
-	return c.doRequest(
+	if err := c.tokenError(token); err != nil {
+		return nil, err
+	}
+
+	ratelimitData, err := c.doRequest(
 		ctx,
 		http.MethodPost,
 		c.getUrl(path, nil, false),
@@ -76,6 +83,13 @@ func (c *Client) post(
 		WithBearerToken(token),
 		uhttp.WithFormBody(toValues(payload)),
 	)
+
+	var tokenErr *pkg.TokenHealthError
+	if errors.As(err, &tokenErr) {
+		c.setTokenError(token, tokenErr)
+	}
+
+	return ratelimitData, err
 }
 
 func (c *Client) getScim(
@@ -87,12 +101,32 @@ func (c *Client) getScim(
 	*v2.RateLimitDescription,
 	error,
 ) {
-	return c.doRequest(
+	return c.doScimRequest(
 		ctx,
+		c.wrapper,
 		http.MethodGet,
 		c.getUrl(path, queryParameters, true),
 		&target,
-		WithBearerToken(c.token),
+		nil,
+	)
+}
+
+func (c *Client) postScim(
+	ctx context.Context,
+	path string,
+	target interface{},
+	payload interface{},
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	return c.doScimRequest(
+		ctx,
+		c.wrapper,
+		http.MethodPost,
+		c.getUrl(path, nil, true),
+		&target,
+		payload,
 	)
 }
 
@@ -100,19 +134,145 @@ func (c *Client) patchScim(
 	ctx context.Context,
 	path string,
 	target interface{},
-	payload []byte,
+	payload interface{},
 ) (
 	*v2.RateLimitDescription,
 	error,
 ) {
-	return c.doRequest(
+	return c.doScimRequest(
 		ctx,
+		c.wrapper,
 		http.MethodPatch,
 		c.getUrl(path, nil, true),
 		&target,
+		payload,
+	)
+}
+
+func (c *Client) deleteScim(
+	ctx context.Context,
+	path string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	return c.doScimRequest(
+		ctx,
+		c.wrapper,
+		http.MethodDelete,
+		c.getUrl(path, nil, true),
+		nil,
+		nil,
+	)
+}
+
+// doScimRequest - the SCIM API reports failures with a non-2xx status and a
+// JSON error body, so unlike the Web API we need to parse the body of failed
+// responses in order to return something meaningful.
+func (c *Client) doScimRequest(
+	ctx context.Context,
+	wrapper *uhttp.BaseHttpClient,
+	method string,
+	url *url.URL,
+	target interface{},
+	payload interface{},
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+	logger.Debug(
+		"making SCIM request",
+		zap.String("method", method),
+		zap.String("url", url.String()),
+	)
+
+	options := []uhttp.RequestOption{
 		WithBearerToken(c.token),
-		uhttp.WithJSONBody(payload),
+		uhttp.WithAcceptJSONHeader(),
+	}
+	if payload != nil {
+		options = append(options, uhttp.WithJSONBody(payload))
+	}
+
+	var (
+		ratelimitData v2.RateLimitDescription
+		response      *http.Response
 	)
+	for attempt := 1; ; attempt++ {
+		request, err := wrapper.NewRequest(
+			ctx,
+			method,
+			url,
+			options...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err = wrapper.Do(
+			request,
+			uhttp.WithRatelimitData(&ratelimitData),
+		)
+		if err == nil {
+			break
+		}
+		if response == nil {
+			return &ratelimitData, err
+		}
+
+		opErr := err
+		if scimErr := parseSCIMError(response); scimErr != nil {
+			opErr = scimErr
+		}
+		if response.StatusCode != http.StatusTooManyRequests {
+			return &ratelimitData, opErr
+		}
+
+		// Large IDP group syncs hit the SCIM rate limits, so wait as long as
+		// Slack asks before failing the operation.
+		wait := retryAfter(response.Header, time.Now())
+		if wait == 0 {
+			wait = time.Duration(attempt) * time.Second
+		}
+		if attempt > c.scimMaxRetries || wait > SCIMMaxRetryWait {
+			return &ratelimitData, &RateLimitError{
+				RetryAfter: wait,
+				Attempts:   attempt,
+				Err:        opErr,
+			}
+		}
+
+		logger.Debug(
+			"SCIM request rate limited, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_after", wait),
+		)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &ratelimitData, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return &ratelimitData, err
+	}
+
+	// Some SCIM operations (i.e. DELETE) respond with an empty body.
+	if len(bodyBytes) == 0 {
+		return &ratelimitData, nil
+	}
+
+	if err := json.Unmarshal(bodyBytes, &target); err != nil {
+		return nil, err
+	}
+
+	return &ratelimitData, nil
 }
 
 func (c *Client) doRequest(
@@ -165,5 +325,14 @@ func (c *Client) doRequest(
 		return nil, err
 	}
 
+	// A deactivated or revoked token will fail every subsequent request, so
+	// surface it as a distinct error instead of a regular API failure.
+	var baseResponse BaseResponse
+	if err := json.Unmarshal(bodyBytes, &baseResponse); err == nil {
+		if tokenErr := pkg.NewTokenHealthError(baseResponse.Error); tokenErr != nil {
+			return &ratelimitData, tokenErr
+		}
+	}
+
 	return &ratelimitData, nil
 }
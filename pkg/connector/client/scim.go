@@ -0,0 +1,216 @@
+package enterprise
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SCIM API versions. Slack serves both on commercial workspaces while GovSlack
+// only serves v1. Responses decode into the same models for both versions:
+// encoding/json matches field names case-insensitively, which covers the
+// attribute casing differences of v1.
+const (
+	SCIMVersion1 = "v1"
+	SCIMVersion2 = "v2"
+)
+
+const (
+	scimV1CoreSchema       = "urn:scim:schemas:core:1.0"
+	scimV1EnterpriseSchema = "urn:scim:schemas:extension:enterprise:1.0"
+	scimV2UserSchema       = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimV2EnterpriseSchema = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	scimV2PatchOpSchema    = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+)
+
+// ClientOption configures optional behavior of the client.
+type ClientOption func(*Client)
+
+// WithGovSlack points the client at the GovSlack endpoints. Unless overridden
+// with WithSCIMVersion, SCIM v1 is used.
+func WithGovSlack(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.govSlack = enabled
+	}
+}
+
+// WithSCIMVersion chooses the SCIM API version, SCIMVersion1 or SCIMVersion2.
+// An empty version picks the default for the environment.
+func WithSCIMVersion(version string) ClientOption {
+	return func(c *Client) {
+		c.scimVersion = version
+	}
+}
+
+// WithSCIMMaxRetries bounds how many times a rate limited SCIM request is
+// retried, after waiting as long as Slack asks in Retry-After, before the
+// RateLimitError is returned. Zero disables the retries.
+func WithSCIMMaxRetries(retries int) ClientOption {
+	return func(c *Client) {
+		c.scimMaxRetries = retries
+	}
+}
+
+// SCIMVersion returns the SCIM API version in use, SCIMVersion1 or
+// SCIMVersion2.
+func (c *Client) SCIMVersion() string {
+	return c.scimVersion
+}
+
+// userSchemas returns the schemas of a user in the configured SCIM version.
+func (c *Client) userSchemas() []string {
+	if c.scimVersion == SCIMVersion1 {
+		return []string{scimV1CoreSchema}
+	}
+	return []string{scimV2UserSchema}
+}
+
+// PatchOpV1 - SCIM v1 has no PatchOp message. A PATCH carries the partial
+// resource instead, and members are removed by tagging them with the delete
+// operation.
+type PatchOpV1 struct {
+	Schemas []string         `json:"schemas"`
+	Members []MemberChangeV1 `json:"members"`
+}
+
+type MemberChangeV1 struct {
+	Value     string `json:"value"`
+	Operation string `json:"operation,omitempty"`
+}
+
+// encodeGroupPatch returns the request body for a group membership change in
+// the format of the configured SCIM version. Patches are always built as v2
+// operations; group is the state they were built from.
+func (c *Client) encodeGroupPatch(group *GroupResource, op PatchOp) interface{} {
+	if c.scimVersion != SCIMVersion1 {
+		return op
+	}
+
+	rv := PatchOpV1{
+		Schemas: []string{scimV1CoreSchema},
+	}
+	for _, operation := range op.Operations {
+		if userID, ok := parseMemberFilterPath(operation.Path); ok {
+			if operation.Op == "remove" {
+				rv.Members = append(rv.Members, MemberChangeV1{Value: userID, Operation: "delete"})
+			}
+			continue
+		}
+		if operation.Path != "members" {
+			continue
+		}
+
+		switch operation.Op {
+		case "add":
+			for _, member := range operation.Value {
+				rv.Members = append(rv.Members, MemberChangeV1{Value: member.Value})
+			}
+		case "remove":
+			for _, member := range operation.Value {
+				rv.Members = append(rv.Members, MemberChangeV1{Value: member.Value, Operation: "delete"})
+			}
+		case "replace":
+			// v1 can't replace the member list, so send the difference.
+			wanted := make(map[string]bool, len(operation.Value))
+			for _, member := range operation.Value {
+				wanted[member.Value] = true
+			}
+			current := make(map[string]bool, len(group.Members))
+			for _, member := range group.Members {
+				current[member.Value] = true
+				if !wanted[member.Value] {
+					rv.Members = append(rv.Members, MemberChangeV1{Value: member.Value, Operation: "delete"})
+				}
+			}
+			for _, member := range operation.Value {
+				if !current[member.Value] {
+					rv.Members = append(rv.Members, MemberChangeV1{Value: member.Value})
+				}
+			}
+		}
+	}
+	return rv
+}
+
+// memberFilterPath returns the path of a single member of a group, which
+// removes just that member when used with the remove operation.
+func memberFilterPath(userID string) string {
+	return fmt.Sprintf(`members[value eq "%s"]`, userID)
+}
+
+// parseMemberFilterPath returns the user ID of a path built by
+// memberFilterPath.
+func parseMemberFilterPath(path string) (string, bool) {
+	userID, ok := strings.CutPrefix(path, `members[value eq "`)
+	if !ok {
+		return "", false
+	}
+	return strings.CutSuffix(userID, `"]`)
+}
+
+// UserAttributes are the attributes of a SCIM user that can be written back.
+// Empty attributes are left unchanged.
+type UserAttributes struct {
+	Title       string
+	Department  string
+	DisplayName string
+	// ManagerID is the Slack user ID of the manager.
+	ManagerID string
+}
+
+// IsEmpty reports whether no attribute is set.
+func (a UserAttributes) IsEmpty() bool {
+	return a == UserAttributes{}
+}
+
+// encodeUserPatch returns the request body that updates the given attributes
+// of a user in the format of the configured SCIM version: replace operations
+// in v2, and the partial resource in v1.
+func (c *Client) encodeUserPatch(attributes UserAttributes) interface{} {
+	if c.scimVersion == SCIMVersion1 {
+		rv := map[string]interface{}{
+			"schemas": []string{scimV1CoreSchema, scimV1EnterpriseSchema},
+		}
+		if attributes.Title != "" {
+			rv["title"] = attributes.Title
+		}
+		if attributes.DisplayName != "" {
+			rv["displayName"] = attributes.DisplayName
+		}
+		extension := make(map[string]interface{})
+		if attributes.Department != "" {
+			extension["department"] = attributes.Department
+		}
+		if attributes.ManagerID != "" {
+			extension["manager"] = map[string]string{"managerId": attributes.ManagerID}
+		}
+		if len(extension) > 0 {
+			rv[scimV1EnterpriseSchema] = extension
+		}
+		return rv
+	}
+
+	var operations []map[string]interface{}
+	replace := func(path string, value interface{}) {
+		operations = append(operations, map[string]interface{}{
+			"op":    "replace",
+			"path":  path,
+			"value": value,
+		})
+	}
+	if attributes.Title != "" {
+		replace("title", attributes.Title)
+	}
+	if attributes.DisplayName != "" {
+		replace("displayName", attributes.DisplayName)
+	}
+	if attributes.Department != "" {
+		replace(scimV2EnterpriseSchema+":department", attributes.Department)
+	}
+	if attributes.ManagerID != "" {
+		replace(scimV2EnterpriseSchema+":manager", map[string]string{"value": attributes.ManagerID})
+	}
+	return map[string]interface{}{
+		"schemas":    []string{scimV2PatchOpSchema},
+		"Operations": operations,
+	}
+}
@@ -6,24 +6,77 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/slack-go/slack"
+	"go.uber.org/zap"
 )
 
 const (
 	PageSizeDefault = 100
+
+	// ConversationSearchPageSize is the largest page admin.conversations.search
+	// returns.
+	ConversationSearchPageSize = 20
+
+	// ConversationLookupMaxPages bounds how many pages of search results are
+	// looked through for a channel name.
+	ConversationLookupMaxPages = 50
+
+	// UsersInfoBatchSize is how many users are looked up per users.info call.
+	UsersInfoBatchSize = 100
+
+	// AccessLogsPageSize and AccessLogsMaxPage are the limits of team.accessLogs.
+	AccessLogsPageSize = 1000
+	AccessLogsMaxPage  = 100
+
+	// SCIMGroupMembersPageSize is how many members of an IDP group are
+	// requested at once.
+	SCIMGroupMembersPageSize = 1000
+
+	// SCIMMaxPages bounds how many pages of a SCIM listing are fetched, so that
+	// a response with a bogus totalResults can't keep a sync paging forever.
+	SCIMMaxPages = 1000
+
+	// DefaultSCIMMaxRetries is how many times a rate limited SCIM request is
+	// retried unless WithSCIMMaxRetries says otherwise.
+	DefaultSCIMMaxRetries = 3
+
+	// SCIMMaxRetryWait bounds the wait before a SCIM retry, whatever
+	// Retry-After asks for.
+	SCIMMaxRetryWait = 5 * time.Minute
+
+	// MaxGroupPatchAttempts bounds how many times an IDP group membership change
+	// is retried when it races another writer.
+	MaxGroupPatchAttempts = 3
 )
 
 type Client struct {
+	baseAuditUrl *url.URL
 	baseScimUrl  *url.URL
 	baseUrl      *url.URL
 	token        string
 	enterpriseID string
 	botToken     string
 	ssoEnabled   bool
-	wrapper      *uhttp.BaseHttpClient
+	govSlack     bool
+	scimVersion  string
+	// scimMaxRetries bounds the retries of rate limited SCIM requests.
+	scimMaxRetries int
+	wrapper        *uhttp.BaseHttpClient
+	// uncachedWrapper is used for reads that must reflect the current state.
+	uncachedWrapper *uhttp.BaseHttpClient
+
+	// deadTokens remembers tokens Slack reported as deactivated or revoked so
+	// that we fail fast instead of repeating the request for every resource.
+	deadTokensMu sync.Mutex
+	deadTokens   map[string]error
 }
 
 func NewClient(
@@ -32,26 +85,83 @@ func NewClient(
 	botToken string,
 	enterpriseID string,
 	ssoEnabled bool,
+	opts ...ClientOption,
 ) (*Client, error) {
-	baseUrl0, err := url.Parse(baseUrl)
+	c := &Client{
+		token:        token,
+		enterpriseID: enterpriseID,
+		botToken:     botToken,
+		ssoEnabled:   ssoEnabled,
+		deadTokens:   make(map[string]error),
+		// Options may override the default.
+		scimMaxRetries: DefaultSCIMMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	rawBaseUrl, rawBaseScimUrl, rawBaseAuditUrl := baseUrl, baseScimUrl, baseAuditUrl
+	if c.govSlack {
+		rawBaseUrl, rawBaseScimUrl, rawBaseAuditUrl = govBaseUrl, govBaseScimUrl, govBaseAuditUrl
+	}
+
+	switch c.scimVersion {
+	case "":
+		// GovSlack only serves SCIM v1.
+		c.scimVersion = SCIMVersion2
+		if c.govSlack {
+			c.scimVersion = SCIMVersion1
+		}
+	case SCIMVersion1, SCIMVersion2:
+	default:
+		return nil, fmt.Errorf("baton-slack: unsupported SCIM version %q", c.scimVersion)
+	}
+
+	baseUrl0, err := url.Parse(rawBaseUrl)
 	if err != nil {
 		return nil, err
 	}
 
-	baseScimUrl0, err := url.Parse(baseScimUrl)
+	baseScimUrl0, err := url.Parse(rawBaseScimUrl)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{
-		baseUrl:      baseUrl0,
-		baseScimUrl:  baseScimUrl0,
-		token:        token,
-		enterpriseID: enterpriseID,
-		botToken:     botToken,
-		ssoEnabled:   ssoEnabled,
-		wrapper:      uhttp.NewBaseHttpClient(httpClient),
-	}, nil
+	baseAuditUrl0, err := url.Parse(rawBaseAuditUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	uncachedWrapper, err := uhttp.NewBaseHttpClientWithContext(
+		context.WithValue(
+			context.Background(),
+			uhttp.ContextKey{},
+			uhttp.CacheConfig{DisableCache: true},
+		),
+		httpClient,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c.baseUrl = baseUrl0
+	c.baseAuditUrl = baseAuditUrl0
+	c.baseScimUrl = baseScimUrl0.JoinPath("scim", c.scimVersion)
+	c.wrapper = uhttp.NewBaseHttpClient(httpClient)
+	c.uncachedWrapper = uncachedWrapper
+	return c, nil
+}
+
+func (c *Client) tokenError(token string) error {
+	c.deadTokensMu.Lock()
+	defer c.deadTokensMu.Unlock()
+	return c.deadTokens[token]
+}
+
+func (c *Client) setTokenError(token string, err error) {
+	c.deadTokensMu.Lock()
+	defer c.deadTokensMu.Unlock()
+	c.deadTokens[token] = err
 }
 
 // handleError - Slack can return a 200 with an error in the JSON body.
@@ -63,13 +173,12 @@ func (a BaseResponse) handleError(err error, action string) error {
 	}
 
 	if a.Error != "" {
-		return fmt.Errorf(
-			"baton-slack: error %s: error %v needed %v provided %v",
-			action,
-			a.Error,
-			a.Needed,
-			a.Provided,
-		)
+		return &APIError{
+			Action:   action,
+			Code:     a.Error,
+			Needed:   a.Needed,
+			Provided: a.Provided,
+		}
 	}
 	return nil
 }
@@ -102,6 +211,46 @@ func (c *Client) GetUserInfo(
 	return response.User, ratelimitData, nil
 }
 
+// GetUsersInfoBatch returns the users with the given IDs. users.info accepts a
+// comma-separated list of IDs, so the users are looked up UsersInfoBatchSize
+// at a time instead of one call per user. Users Slack doesn't know about are
+// left out of the result.
+func (c *Client) GetUsersInfoBatch(
+	ctx context.Context,
+	userIDs []string,
+) (
+	[]User,
+	*v2.RateLimitDescription,
+	error,
+) {
+	var (
+		rv            = make([]User, 0, len(userIDs))
+		ratelimitData *v2.RateLimitDescription
+	)
+	for start := 0; start < len(userIDs); start += UsersInfoBatchSize {
+		end := min(start+UsersInfoBatchSize, len(userIDs))
+
+		var response struct {
+			BaseResponse
+			Users []User `json:"users"`
+		}
+		rl, err := c.post(
+			ctx,
+			UrlPathGetUserInfo,
+			&response,
+			map[string]interface{}{"users": strings.Join(userIDs[start:end], ",")},
+			true,
+		)
+		ratelimitData = rl
+		if err := response.handleError(err, "fetching users info"); err != nil {
+			return nil, ratelimitData, err
+		}
+		rv = append(rv, response.Users...)
+	}
+
+	return rv, ratelimitData, nil
+}
+
 // GetUserGroupMembers returns the members of the given user group from a given team.
 func (c *Client) GetUserGroupMembers(
 	ctx context.Context,
@@ -134,6 +283,36 @@ func (c *Client) GetUserGroupMembers(
 	return response.Users, ratelimitData, nil
 }
 
+// UpdateUserGroupMembers replaces the members of the given user group. Slack
+// doesn't allow a user group without members, so userIDs can't be empty.
+func (c *Client) UpdateUserGroupMembers(
+	ctx context.Context,
+	userGroupID string,
+	teamID string,
+	userIDs []string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{
+		"usergroup": userGroupID,
+		"users":     strings.Join(userIDs, ","),
+	}
+	if teamID != "" {
+		values["team_id"] = teamID
+	}
+
+	var response BaseResponse
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathUpdateUserGroupMembers,
+		&response,
+		values,
+		true,
+	)
+	return ratelimitData, response.handleError(err, "updating user group members")
+}
+
 // GetUsersAdmin returns all users in Enterprise grid.
 func (c *Client) GetUsersAdmin(
 	ctx context.Context,
@@ -172,6 +351,47 @@ func (c *Client) GetUsersAdmin(
 	return response.Users, nextToken, ratelimitData, nil
 }
 
+// GetTeamUsersAdmin returns the users of the given team using the admin API,
+// which doesn't require the bot to be installed in the team.
+func (c *Client) GetTeamUsersAdmin(
+	ctx context.Context,
+	teamID string,
+	cursor string,
+) (
+	[]UserAdmin,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{"team_id": teamID}
+
+	if cursor != "" {
+		values["cursor"] = cursor
+	}
+
+	var response struct {
+		BaseResponse
+		Users []UserAdmin `json:"users"`
+		Pagination
+	}
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathGetUsersAdmin,
+		&response,
+		values,
+		false,
+	)
+	if err := response.handleError(err, "fetching team users"); err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	return response.Users,
+		response.ResponseMetadata.NextCursor,
+		ratelimitData,
+		nil
+}
+
 // GetUsers returns the users of the given team.
 func (c *Client) GetUsers(
 	ctx context.Context,
@@ -253,21 +473,21 @@ func (c *Client) GetTeams(
 		nil
 }
 
-// GetRoleAssignments returns the role assignments for the given role ID.
-func (c *Client) GetRoleAssignments(
+// GetAuthPolicyEntities returns the entities assigned to the given
+// authentication policy.
+func (c *Client) GetAuthPolicyEntities(
 	ctx context.Context,
-	roleID string,
+	policyName string,
 	cursor string,
 ) (
-	[]RoleAssignment,
+	[]AuthPolicyEntity,
 	string,
 	*v2.RateLimitDescription,
 	error,
 ) {
-	values := map[string]interface{}{}
-
-	if roleID != "" {
-		values["role_ids"] = roleID
+	values := map[string]interface{}{
+		"policy_name": policyName,
+		"entity_type": "USER",
 	}
 
 	if cursor != "" {
@@ -276,236 +496,1523 @@ func (c *Client) GetRoleAssignments(
 
 	var response struct {
 		BaseResponse
-		RoleAssignments []RoleAssignment `json:"role_assignments"`
+		Entities []AuthPolicyEntity `json:"entities"`
 		Pagination
 	}
 
 	ratelimitData, err := c.post(
 		ctx,
-		UrlPathGetRoleAssignments,
+		UrlPathAuthPolicyEntities,
 		&response,
 		values,
 		false,
 	)
-	if err := response.handleError(err, "fetching role assignments"); err != nil {
+	if err := response.handleError(err, "fetching auth policy entities"); err != nil {
 		return nil, "", ratelimitData, err
 	}
 
-	return response.RoleAssignments,
+	return response.Entities,
 		response.ResponseMetadata.NextCursor,
 		ratelimitData,
 		nil
 }
 
-// GetUserGroups returns the user groups for the given team.
-func (c *Client) GetUserGroups(
+// AssignAuthPolicy assigns the given user to an authentication policy.
+func (c *Client) AssignAuthPolicy(
 	ctx context.Context,
-	teamID string,
+	policyName string,
+	userID string,
 ) (
-	[]slack.UserGroup,
 	*v2.RateLimitDescription,
 	error,
 ) {
-	var response struct {
-		BaseResponse
-		UserGroups []slack.UserGroup `json:"usergroups"`
-	}
-
-	ratelimitData, err := c.post(
-		ctx,
-		UrlPathGetUserGroups,
-		&response,
-		map[string]interface{}{"team_id": teamID},
-		// The bot token needed here because user token doesn't work unless user
-		// is in all workspaces.
-		true,
-	)
-	if err := response.handleError(err, "fetching user groups"); err != nil {
-		return nil, ratelimitData, err
-	}
-
-	return response.UserGroups, ratelimitData, nil
+	return c.updateAuthPolicy(ctx, UrlPathAuthPolicyAssign, policyName, userID, "assigning auth policy")
 }
 
-// SetWorkspaceRole sets the role for the given user in the given team.
-func (c *Client) SetWorkspaceRole(
+// RemoveAuthPolicy removes the given user from an authentication policy.
+func (c *Client) RemoveAuthPolicy(
 	ctx context.Context,
-	teamID string,
+	policyName string,
 	userID string,
-	roleID string,
 ) (
 	*v2.RateLimitDescription,
 	error,
 ) {
-	actionUrl, err := getWorkspaceUrlPathByRole(roleID)
-	if err != nil {
-		return nil, err
-	}
-
-	var response BaseResponse
-
-	ratelimitData, err := c.post(
-		ctx,
-		actionUrl,
-		&response,
-		map[string]interface{}{
-			"team_id": teamID,
-			"user_id": userID,
-		},
-		false,
-	)
-	return ratelimitData, response.handleError(err, "setting user role")
+	return c.updateAuthPolicy(ctx, UrlPathAuthPolicyRemove, policyName, userID, "removing auth policy")
 }
 
-// ListIDPGroups returns all IDP groups from the SCIM API.
-func (c *Client) ListIDPGroups(
+func (c *Client) updateAuthPolicy(
 	ctx context.Context,
-	startIndex int,
-	count int,
+	path string,
+	policyName string,
+	userID string,
+	action string,
 ) (
-	*SCIMResponse[GroupResource],
 	*v2.RateLimitDescription,
 	error,
 ) {
-	var response SCIMResponse[GroupResource]
-	ratelimitData, err := c.getScim(
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
 		ctx,
-		UrlPathIDPGroups,
+		path,
 		&response,
 		map[string]interface{}{
-			"startIndex": startIndex,
-			"count":      count,
+			"policy_name": policyName,
+			"entity_type": "USER",
+			"entity_ids":  userID,
 		},
+		false,
 	)
-	if err != nil {
-		return nil, ratelimitData, fmt.Errorf("error fetching IDP groups: %w", err)
-	}
-
-	return &response, ratelimitData, nil
+	return ratelimitData, response.handleError(err, action)
 }
 
-// GetIDPGroup returns a single IDP group from the SCIM API.
-func (c *Client) GetIDPGroup(
+// ApproveApp approves the installation of an app in a workspace, or in the
+// whole organization when teamID is empty.
+func (c *Client) ApproveApp(
 	ctx context.Context,
-	groupID string,
+	appID string,
+	teamID string,
 ) (
-	*GroupResource,
 	*v2.RateLimitDescription,
 	error,
 ) {
-	var response GroupResource
-	ratelimitData, err := c.getScim(
-		ctx,
-		fmt.Sprintf(UrlPathIDPGroup, groupID),
-		&response,
-		nil,
-	)
-	if err != nil {
-		return nil, ratelimitData, fmt.Errorf("error fetching IDP group: %w", err)
-	}
-
-	return &response, ratelimitData, nil
+	return c.updateApp(ctx, UrlPathAppApprove, appID, teamID, "approving app")
 }
 
-// AddUserToGroup patches a group by adding a user to it.
-func (c *Client) AddUserToGroup(
+// RestrictApp restricts the installation of an app in a workspace, or in the
+// whole organization when teamID is empty.
+func (c *Client) RestrictApp(
 	ctx context.Context,
-	groupID string,
-	user string,
+	appID string,
+	teamID string,
 ) (
 	*v2.RateLimitDescription,
 	error,
 ) {
-	requestBody := PatchOp{
-		Schemas: []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
-		Operations: []ScimOperate{
-			{
-				Op:   "add",
-				Path: "members",
-				Value: []UserID{
-					{Value: user},
-				},
-			},
-		},
-	}
-
-	ratelimitData, err := c.patchGroup(ctx, groupID, requestBody)
-	if err != nil {
-		return ratelimitData, fmt.Errorf("error adding user to IDP group: %w", err)
-	}
-
-	return ratelimitData, nil
+	return c.updateApp(ctx, UrlPathAppRestrict, appID, teamID, "restricting app")
 }
 
-// RemoveUserFromGroup patches a group by removing a user from it.
-func (c *Client) RemoveUserFromGroup(
+// ListApprovedApps returns a page of the apps approved for the given
+// workspace, or for the whole organization when teamID is empty.
+func (c *Client) ListApprovedApps(
 	ctx context.Context,
-	groupID string,
-	user string,
+	teamID string,
+	cursor string,
 ) (
-	bool,
+	[]ApprovedApp,
+	string,
 	*v2.RateLimitDescription,
 	error,
 ) {
-	// First, we need to fetch group to get existing members.
-	group, ratelimitData, err := c.GetIDPGroup(ctx, groupID)
-	if err != nil {
-		return false, ratelimitData, fmt.Errorf("error fetching IDP group: %w", err)
+	values := map[string]interface{}{
+		"limit": PageSizeDefault,
 	}
-
-	found := false
-	var result []UserID
-	for _, member := range group.Members {
-		if member.Value == user {
-			found = true
-		} else {
-			result = append(result, UserID{Value: member.Value})
-		}
+	if teamID != "" {
+		values["team_id"] = teamID
+	} else {
+		values["enterprise_id"] = c.enterpriseID
 	}
 
-	// If we don't find the user, we can short-circuit here.
-	if !found {
-		return false, ratelimitData, nil
+	if cursor != "" {
+		values["cursor"] = cursor
 	}
 
-	requestBody := PatchOp{
-		Schemas: []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
-		Operations: []ScimOperate{
-			{
-				Op:    "replace",
-				Path:  "members",
-				Value: result,
-			},
-		},
+	var response struct {
+		BaseResponse
+		ApprovedApps []ApprovedApp `json:"approved_apps"`
+		Pagination
 	}
 
-	ratelimitData, err = c.patchGroup(ctx, groupID, requestBody)
-	if err != nil {
-		return false, ratelimitData, fmt.Errorf("error removing user from IDP group: %w", err)
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathAppsApproved,
+		&response,
+		values,
+		false,
+	)
+	if err := response.handleError(err, "fetching approved apps"); err != nil {
+		return nil, "", ratelimitData, err
 	}
 
-	return true, ratelimitData, nil
+	return response.ApprovedApps,
+		response.ResponseMetadata.NextCursor,
+		ratelimitData,
+		nil
 }
 
-func (c *Client) patchGroup(
+// ListAppRequests returns a page of the pending requests to install an app in
+// the given workspace.
+func (c *Client) ListAppRequests(
 	ctx context.Context,
-	groupID string,
-	requestBody PatchOp,
+	teamID string,
+	cursor string,
 ) (
+	[]AppRequest,
+	string,
 	*v2.RateLimitDescription,
 	error,
 ) {
-	payload, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, err
+	values := map[string]interface{}{
+		"limit":   PageSizeDefault,
+		"team_id": teamID,
 	}
 
+	if cursor != "" {
+		values["cursor"] = cursor
+	}
+
+	var response struct {
+		BaseResponse
+		AppRequests []AppRequest `json:"app_requests"`
+		Pagination
+	}
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathAppRequests,
+		&response,
+		values,
+		false,
+	)
+	if err := response.handleError(err, "fetching app requests"); err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	return response.AppRequests,
+		response.ResponseMetadata.NextCursor,
+		ratelimitData,
+		nil
+}
+
+func (c *Client) updateApp(
+	ctx context.Context,
+	path string,
+	appID string,
+	teamID string,
+	action string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{
+		"app_id": appID,
+	}
+	if teamID != "" {
+		values["team_id"] = teamID
+	} else {
+		values["enterprise_id"] = c.enterpriseID
+	}
+
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		path,
+		&response,
+		values,
+		false,
+	)
+	return ratelimitData, response.handleError(err, action)
+}
+
+// SearchConversations returns a page of the public and private channels of the
+// whole organization whose name matches the query.
+func (c *Client) SearchConversations(
+	ctx context.Context,
+	query string,
+	cursor string,
+) (
+	[]AdminConversation,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{
+		"query":                query,
+		"limit":                ConversationSearchPageSize,
+		"search_channel_types": "public,private",
+	}
+
+	if cursor != "" {
+		values["cursor"] = cursor
+	}
+
+	var response struct {
+		BaseResponse
+		Conversations []AdminConversation `json:"conversations"`
+		NextCursor    string              `json:"next_cursor"`
+	}
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathConversationSearch,
+		&response,
+		values,
+		false,
+	)
+	if err := response.handleError(err, "searching channels"); err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	return response.Conversations, response.NextCursor, ratelimitData, nil
+}
+
+// LookupConversationByName returns the channel of the organization with the
+// given name. A leading # is ignored. The search also matches names partially,
+// so the results are paged through for an exact match.
+func (c *Client) LookupConversationByName(
+	ctx context.Context,
+	name string,
+) (
+	*AdminConversation,
+	*v2.RateLimitDescription,
+	error,
+) {
+	name = strings.TrimPrefix(name, "#")
+
+	var (
+		cursor        string
+		ratelimitData *v2.RateLimitDescription
+	)
+	for page := 0; page < ConversationLookupMaxPages; page++ {
+		conversations, nextCursor, rl, err := c.SearchConversations(ctx, name, cursor)
+		ratelimitData = rl
+		if err != nil {
+			return nil, ratelimitData, err
+		}
+
+		for i, conversation := range conversations {
+			if conversation.Name == name {
+				return &conversations[i], ratelimitData, nil
+			}
+		}
+
+		if nextCursor == "" || nextCursor == cursor {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	return nil, ratelimitData, fmt.Errorf("baton-slack: channel #%s not found", name)
+}
+
+// RenameConversation renames a channel of any workspace of the organization,
+// whether or not the bot is a member of it.
+func (c *Client) RenameConversation(
+	ctx context.Context,
+	channelID string,
+	name string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathConversationRename,
+		&response,
+		map[string]interface{}{
+			"channel_id": channelID,
+			"name":       name,
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "renaming channel")
+}
+
+// ArchiveConversation archives a channel with the admin API, which doesn't
+// require the bot to be a member of the channel.
+func (c *Client) ArchiveConversation(
+	ctx context.Context,
+	channelID string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathConversationArchive,
+		&response,
+		map[string]interface{}{
+			"channel_id": channelID,
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "archiving channel")
+}
+
+// CreateConversation creates a channel with the admin API and returns its ID.
+// The channel belongs to the given workspace, or to the whole organization
+// when teamID is empty.
+func (c *Client) CreateConversation(
+	ctx context.Context,
+	name string,
+	isPrivate bool,
+	teamID string,
+) (
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{
+		"name":       name,
+		"is_private": isPrivate,
+	}
+	if teamID != "" {
+		values["team_id"] = teamID
+	} else {
+		values["org_wide"] = true
+	}
+
+	var response struct {
+		BaseResponse
+		ChannelID string `json:"channel_id"`
+	}
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathConversationCreate,
+		&response,
+		values,
+		false,
+	)
+	if err := response.handleError(err, "creating channel"); err != nil {
+		return "", ratelimitData, err
+	}
+	return response.ChannelID, ratelimitData, nil
+}
+
+// DisconnectSharedChannel disconnects the given organizations or workspaces
+// from a Slack Connect channel, which removes all of their members from it.
+func (c *Client) DisconnectSharedChannel(
+	ctx context.Context,
+	channelID string,
+	leavingTeamIDs []string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathDisconnectShared,
+		&response,
+		map[string]interface{}{
+			"channel_id":       channelID,
+			"leaving_team_ids": strings.Join(leavingTeamIDs, ","),
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "disconnecting shared channel")
+}
+
+// ApproveSharedInvite approves an inbound Slack Connect invitation. targetTeam
+// is the workspace the channel is shared into, on Enterprise Grid.
+func (c *Client) ApproveSharedInvite(
+	ctx context.Context,
+	inviteID string,
+	targetTeam string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	return c.updateSharedInvite(ctx, UrlPathApproveSharedInvite, inviteID, targetTeam, "approving shared invite")
+}
+
+// DeclineSharedInvite declines an inbound Slack Connect invitation.
+func (c *Client) DeclineSharedInvite(
+	ctx context.Context,
+	inviteID string,
+	targetTeam string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	return c.updateSharedInvite(ctx, UrlPathDeclineSharedInvite, inviteID, targetTeam, "declining shared invite")
+}
+
+func (c *Client) updateSharedInvite(
+	ctx context.Context,
+	path string,
+	inviteID string,
+	targetTeam string,
+	action string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{
+		"invite_id": inviteID,
+	}
+	if targetTeam != "" {
+		values["target_team"] = targetTeam
+	}
+
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		path,
+		&response,
+		values,
+		// Slack Connect invitations are managed with the
+		// conversations.connect:manage scope of the bot.
+		true,
+	)
+	return ratelimitData, response.handleError(err, action)
+}
+
+// GetRoleAssignments returns the role assignments for the given role ID.
+func (c *Client) GetRoleAssignments(
+	ctx context.Context,
+	roleID string,
+	cursor string,
+) (
+	[]RoleAssignment,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{}
+
+	if roleID != "" {
+		values["role_ids"] = roleID
+	}
+
+	if cursor != "" {
+		values["cursor"] = cursor
+	}
+
+	var response struct {
+		BaseResponse
+		RoleAssignments []RoleAssignment `json:"role_assignments"`
+		Pagination
+	}
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathGetRoleAssignments,
+		&response,
+		values,
+		false,
+	)
+	if err := response.handleError(err, "fetching role assignments"); err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	return response.RoleAssignments,
+		response.ResponseMetadata.NextCursor,
+		ratelimitData,
+		nil
+}
+
+// AddRoleAssignment assigns a system role to a user, scoped to the given
+// entity: the organization, a workspace or a channel.
+func (c *Client) AddRoleAssignment(
+	ctx context.Context,
+	roleID string,
+	entityID string,
+	userID string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathAddRoleAssignments,
+		&response,
+		map[string]interface{}{
+			"role_id":    roleID,
+			"entity_ids": entityID,
+			"user_ids":   userID,
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "assigning role")
+}
+
+// RemoveRoleAssignment removes a system role from a user, for the given
+// entity: the organization, a workspace or a channel.
+func (c *Client) RemoveRoleAssignment(
+	ctx context.Context,
+	roleID string,
+	entityID string,
+	userID string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathRemoveRoleAssignments,
+		&response,
+		map[string]interface{}{
+			"role_id":    roleID,
+			"entity_ids": entityID,
+			"user_ids":   userID,
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "removing role")
+}
+
+// GetAccessLogs returns a page of the login history of the given team, most
+// recent first. Requires a user token with the admin scope.
+func (c *Client) GetAccessLogs(
+	ctx context.Context,
+	teamID string,
+	page int,
+) (
+	[]slack.Login,
+	*slack.Paging,
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{
+		"count": AccessLogsPageSize,
+		"page":  page,
+	}
+
+	if teamID != "" {
+		values["team_id"] = teamID
+	}
+
+	var response struct {
+		BaseResponse
+		Logins []slack.Login `json:"logins"`
+		Paging slack.Paging  `json:"paging"`
+	}
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathGetAccessLogs,
+		&response,
+		values,
+		false,
+	)
+	if err := response.handleError(err, "fetching access logs"); err != nil {
+		return nil, nil, ratelimitData, err
+	}
+
+	return response.Logins, &response.Paging, ratelimitData, nil
+}
+
+// GetUserGroups returns the user groups for the given team.
+func (c *Client) GetUserGroups(
+	ctx context.Context,
+	teamID string,
+) (
+	[]slack.UserGroup,
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response struct {
+		BaseResponse
+		UserGroups []slack.UserGroup `json:"usergroups"`
+	}
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathGetUserGroups,
+		&response,
+		map[string]interface{}{"team_id": teamID},
+		// The bot token needed here because user token doesn't work unless user
+		// is in all workspaces.
+		true,
+	)
+	if err := response.handleError(err, "fetching user groups"); err != nil {
+		return nil, ratelimitData, err
+	}
+
+	return response.UserGroups, ratelimitData, nil
+}
+
+// LookupUserGroupByHandle returns the user group of the given team with the
+// given handle. Handles are stable across environments, unlike IDs. A leading
+// @ is ignored and the comparison is case-insensitive, like in Slack.
+func (c *Client) LookupUserGroupByHandle(
+	ctx context.Context,
+	teamID string,
+	handle string,
+) (
+	*slack.UserGroup,
+	*v2.RateLimitDescription,
+	error,
+) {
+	handle = strings.TrimPrefix(handle, "@")
+
+	userGroups, ratelimitData, err := c.GetUserGroups(ctx, teamID)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+
+	for i, userGroup := range userGroups {
+		if strings.EqualFold(userGroup.Handle, handle) {
+			return &userGroups[i], ratelimitData, nil
+		}
+	}
+
+	return nil, ratelimitData, fmt.Errorf("baton-slack: user group @%s not found", handle)
+}
+
+// GetConversationTeams returns the workspaces of the organization the given
+// channel is connected to, one page at a time.
+func (c *Client) GetConversationTeams(
+	ctx context.Context,
+	channelID string,
+	cursor string,
+) (
+	[]string,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{"channel_id": channelID}
+
+	if cursor != "" {
+		values["cursor"] = cursor
+	}
+
+	var response struct {
+		BaseResponse
+		TeamIDs []string `json:"team_ids"`
+		Pagination
+	}
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathConversationTeams,
+		&response,
+		values,
+		false,
+	)
+	if err := response.handleError(err, "fetching channel workspaces"); err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	return response.TeamIDs,
+		response.ResponseMetadata.NextCursor,
+		ratelimitData,
+		nil
+}
+
+// GetUserGroupChannels returns the default channels attached to the given
+// organization-level user group.
+func (c *Client) GetUserGroupChannels(
+	ctx context.Context,
+	userGroupID string,
+) (
+	[]UserGroupChannel,
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response struct {
+		BaseResponse
+		Channels []UserGroupChannel `json:"channels"`
+	}
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathUserGroupChannels,
+		&response,
+		map[string]interface{}{
+			"usergroup_id":        userGroupID,
+			"include_num_members": true,
+		},
+		false,
+	)
+	if err := response.handleError(err, "fetching user group channels"); err != nil {
+		return nil, ratelimitData, err
+	}
+
+	return response.Channels, ratelimitData, nil
+}
+
+// GetOrgUserGroupMembers returns the members of the given organization-level
+// user group across all workspaces.
+func (c *Client) GetOrgUserGroupMembers(
+	ctx context.Context,
+	userGroupID string,
+	cursor string,
+) (
+	[]string,
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{"usergroup_id": userGroupID}
+
+	if cursor != "" {
+		values["cursor"] = cursor
+	}
+
+	var response struct {
+		BaseResponse
+		Users []string `json:"users"`
+		Pagination
+	}
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathUserGroupUsers,
+		&response,
+		values,
+		false,
+	)
+	if err := response.handleError(err, "fetching organization user group members"); err != nil {
+		return nil, "", ratelimitData, err
+	}
+
+	return response.Users,
+		response.ResponseMetadata.NextCursor,
+		ratelimitData,
+		nil
+}
+
+// AddUserGroupToTeam attaches the given organization-level user group to a
+// workspace.
+func (c *Client) AddUserGroupToTeam(
+	ctx context.Context,
+	userGroupID string,
+	teamID string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathUserGroupAddTeams,
+		&response,
+		map[string]interface{}{
+			"usergroup_id": userGroupID,
+			"team_ids":     teamID,
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "adding user group to team")
+}
+
+// SetWorkspaceRole sets the role for the given user in the given team.
+func (c *Client) SetWorkspaceRole(
+	ctx context.Context,
+	teamID string,
+	userID string,
+	roleID string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	actionUrl, err := getWorkspaceUrlPathByRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		actionUrl,
+		&response,
+		map[string]interface{}{
+			"team_id": teamID,
+			"user_id": userID,
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "setting user role")
+}
+
+// ResetUserSessions signs a user out of every session, or only of their
+// mobile or web sessions.
+func (c *Client) ResetUserSessions(
+	ctx context.Context,
+	userID string,
+	mobileOnly bool,
+	webOnly bool,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{"user_id": userID}
+	if mobileOnly {
+		values["mobile_only"] = true
+	}
+	if webOnly {
+		values["web_only"] = true
+	}
+
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathResetSessions,
+		&response,
+		values,
+		false,
+	)
+	return ratelimitData, response.handleError(err, "resetting user sessions")
+}
+
+// ClearUserSessionSettings removes the session duration settings set for a
+// user, so that the settings of the organization apply to them again.
+func (c *Client) ClearUserSessionSettings(
+	ctx context.Context,
+	userID string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathClearSessionSettings,
+		&response,
+		map[string]interface{}{
+			"user_ids": userID,
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "clearing user session settings")
+}
+
+// SetUserExpiration sets the Unix time at which a guest account of a
+// workspace is deactivated.
+func (c *Client) SetUserExpiration(
+	ctx context.Context,
+	teamID string,
+	userID string,
+	expiresAt int64,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathSetExpiration,
+		&response,
+		map[string]interface{}{
+			"team_id":       teamID,
+			"user_id":       userID,
+			"expiration_ts": strconv.FormatInt(expiresAt, 10),
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "setting guest expiration")
+}
+
+// AssignUserToTeam adds an existing user of the organization to a workspace
+// as a full member.
+func (c *Client) AssignUserToTeam(
+	ctx context.Context,
+	teamID string,
+	userID string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathAssignUser,
+		&response,
+		map[string]interface{}{
+			"team_id": teamID,
+			"user_id": userID,
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "adding user to workspace")
+}
+
+// RemoveUserFromTeam removes a user from a workspace. The account itself is
+// left untouched.
+func (c *Client) RemoveUserFromTeam(
+	ctx context.Context,
+	teamID string,
+	userID string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathRemoveUser,
+		&response,
+		map[string]interface{}{
+			"team_id": teamID,
+			"user_id": userID,
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "removing user from workspace")
+}
+
+// InviteGuest invites the owner of the given email to a workspace as a guest
+// restricted to the given channels. Single-channel guests are
+// ultra-restricted and must be given exactly one channel. A non-zero
+// expiresAt is the Unix time at which the guest account is deactivated.
+func (c *Client) InviteGuest(
+	ctx context.Context,
+	teamID string,
+	email string,
+	channelIDs []string,
+	ultraRestricted bool,
+	expiresAt int64,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	values := map[string]interface{}{
+		"team_id":     teamID,
+		"email":       email,
+		"channel_ids": strings.Join(channelIDs, ","),
+	}
+	if ultraRestricted {
+		values["is_ultra_restricted"] = true
+	} else {
+		values["is_restricted"] = true
+	}
+	if expiresAt > 0 {
+		values["guest_expiration_ts"] = strconv.FormatInt(expiresAt, 10)
+	}
+
+	return c.inviteUser(ctx, values, "inviting guest")
+}
+
+// InviteUser invites the owner of the given email to a workspace as a full
+// member, with the given channels as their default channels.
+func (c *Client) InviteUser(
+	ctx context.Context,
+	teamID string,
+	email string,
+	channelIDs []string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	return c.inviteUser(
+		ctx,
+		map[string]interface{}{
+			"team_id":     teamID,
+			"email":       email,
+			"channel_ids": strings.Join(channelIDs, ","),
+		},
+		"inviting user",
+	)
+}
+
+func (c *Client) inviteUser(
+	ctx context.Context,
+	values map[string]interface{},
+	action string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response BaseResponse
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathInviteUser,
+		&response,
+		values,
+		false,
+	)
+	return ratelimitData, response.handleError(err, action)
+}
+
+// SetUserProfile updates the given fields of a user profile. Setting another
+// user's profile requires an admin token, and fields managed by the IDP can't
+// be changed this way.
+func (c *Client) SetUserProfile(
+	ctx context.Context,
+	userID string,
+	profile map[string]interface{},
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	encodedProfile, err := json.Marshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("baton-slack: error encoding user profile: %w", err)
+	}
+
+	var response BaseResponse
+
+	ratelimitData, err := c.post(
+		ctx,
+		UrlPathSetUserProfile,
+		&response,
+		map[string]interface{}{
+			"user":    userID,
+			"profile": string(encodedProfile),
+		},
+		false,
+	)
+	return ratelimitData, response.handleError(err, "setting user profile")
+}
+
+// ListIDPGroups returns all IDP groups from the SCIM API.
+func (c *Client) ListIDPGroups(
+	ctx context.Context,
+	startIndex int,
+	count int,
+) (
+	*SCIMResponse[GroupResource],
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response SCIMResponse[GroupResource]
+	ratelimitData, err := c.getScim(
+		ctx,
+		UrlPathIDPGroups,
+		&response,
+		map[string]interface{}{
+			"startIndex": startIndex,
+			"count":      count,
+		},
+	)
+	if err != nil {
+		return nil, ratelimitData, fmt.Errorf("error fetching IDP groups: %w", err)
+	}
+
+	return &response, ratelimitData, nil
+}
+
+// LookupIDPGroupByDisplayName returns the IDP group with the given display
+// name. The SCIM filter is case-insensitive, so the
+// results are checked for an exact match first.
+func (c *Client) LookupIDPGroupByDisplayName(
+	ctx context.Context,
+	displayName string,
+) (
+	*GroupResource,
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response SCIMResponse[GroupResource]
+	ratelimitData, err := c.getScim(
+		ctx,
+		UrlPathIDPGroups,
+		&response,
+		map[string]interface{}{
+			"filter": fmt.Sprintf("displayName eq %q", displayName),
+		},
+	)
+	if err != nil {
+		return nil, ratelimitData, fmt.Errorf("error looking up IDP group: %w", err)
+	}
+
+	var match *GroupResource
+	for i, group := range response.Resources {
+		if group.DisplayName == displayName {
+			return &response.Resources[i], ratelimitData, nil
+		}
+		if match == nil && strings.EqualFold(group.DisplayName, displayName) {
+			match = &response.Resources[i]
+		}
+	}
+	if match != nil {
+		return match, ratelimitData, nil
+	}
+
+	return nil, ratelimitData, fmt.Errorf("baton-slack: IDP group %q not found", displayName)
+}
+
+// ListIDPUsers returns a page of users from the SCIM API.
+func (c *Client) ListIDPUsers(
+	ctx context.Context,
+	startIndex int,
+	count int,
+) (
+	*SCIMResponse[SCIMUser],
+	*v2.RateLimitDescription,
+	error,
+) {
+	var response SCIMResponse[SCIMUser]
+	ratelimitData, err := c.getScim(
+		ctx,
+		UrlPathIDPUsers,
+		&response,
+		map[string]interface{}{
+			"startIndex": startIndex,
+			"count":      count,
+		},
+	)
+	if err != nil {
+		return nil, ratelimitData, fmt.Errorf("error fetching IDP users: %w", err)
+	}
+
+	return &response, ratelimitData, nil
+}
+
+// CreateUser creates an active user through SCIM and returns its ID. Unlike
+// admin.users.invite, the user is added to the organization right away,
+// without an invitation to accept.
+func (c *Client) CreateUser(
+	ctx context.Context,
+	userName string,
+	email string,
+	givenName string,
+	familyName string,
+) (
+	string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	user := SCIMNewUser{
+		Schemas:  c.userSchemas(),
+		UserName: userName,
+		Emails:   []Email{{Value: email, Primary: true}},
+		Active:   true,
+	}
+	if givenName != "" || familyName != "" {
+		user.Name = &Name{GivenName: givenName, FamilyName: familyName}
+		user.DisplayName = strings.TrimSpace(givenName + " " + familyName)
+	}
+
+	var response SCIMUser
+	ratelimitData, err := c.postScim(ctx, UrlPathIDPUsers, &response, user)
+	if err != nil {
+		return "", ratelimitData, fmt.Errorf("error creating user: %w", err)
+	}
+	return response.ID, ratelimitData, nil
+}
+
+// UpdateUser writes the given attributes to a user through SCIM, e.g. when the
+// identity provider data managed elsewhere is the source of truth.
+func (c *Client) UpdateUser(
+	ctx context.Context,
+	userID string,
+	attributes UserAttributes,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	if attributes.IsEmpty() {
+		return nil, nil
+	}
+
+	var response SCIMUser
+	ratelimitData, err := c.patchScim(
+		ctx,
+		fmt.Sprintf(UrlPathIDPUser, userID),
+		&response,
+		c.encodeUserPatch(attributes),
+	)
+	if err != nil {
+		return ratelimitData, fmt.Errorf("error updating user: %w", err)
+	}
+	return ratelimitData, nil
+}
+
+// DeactivateUser deactivates a user through SCIM. The user is signed out of
+// every workspace and can't sign back in until reactivated.
+func (c *Client) DeactivateUser(
+	ctx context.Context,
+	userID string,
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
+	ratelimitData, err := c.deleteScim(ctx, fmt.Sprintf(UrlPathIDPUser, userID))
+	if err != nil {
+		return ratelimitData, fmt.Errorf("error deactivating user: %w", err)
+	}
+	return ratelimitData, nil
+}
+
+// GetIDPGroup fetches a group along with all of its members. See
+// getIDPGroupPages.
+func (c *Client) GetIDPGroup(
+	ctx context.Context,
+	groupID string,
+) (
+	*GroupResource,
+	*v2.RateLimitDescription,
+	error,
+) {
+	return c.getIDPGroupPages(ctx, c.wrapper, groupID)
+}
+
+// getIDPGroupUncached fetches a group bypassing the HTTP cache. Provisioning
+// needs the current member list, not the one we saw during the last sync.
+func (c *Client) getIDPGroupUncached(
+	ctx context.Context,
+	groupID string,
+) (
+	*GroupResource,
+	*v2.RateLimitDescription,
+	error,
+) {
+	return c.getIDPGroupPages(ctx, c.uncachedWrapper, groupID)
+}
+
+// getIDPGroupPages fetches a group with its members in pages of
+// SCIMGroupMembersPageSize. Slack truncates the member list of very large
+// groups, so members are requested with startIndex and count until a short
+// page. Servers that ignore the paging parameters return the same members
+// again, which ends the paging as well.
+func (c *Client) getIDPGroupPages(
+	ctx context.Context,
+	wrapper *uhttp.BaseHttpClient,
+	groupID string,
+) (
+	*GroupResource,
+	*v2.RateLimitDescription,
+	error,
+) {
+	var (
+		group         *GroupResource
+		ratelimitData *v2.RateLimitDescription
+		seen          = make(map[string]bool)
+	)
+	startIndex := 1
+	for page := 1; ; page++ {
+		if page > SCIMMaxPages {
+			return nil, ratelimitData, fmt.Errorf(
+				"error fetching IDP group: members exceeded %d pages",
+				SCIMMaxPages,
+			)
+		}
+
+		var response GroupResource
+		rl, err := c.doScimRequest(
+			ctx,
+			wrapper,
+			http.MethodGet,
+			c.getUrl(
+				fmt.Sprintf(UrlPathIDPGroup, groupID),
+				map[string]interface{}{
+					"startIndex": startIndex,
+					"count":      SCIMGroupMembersPageSize,
+				},
+				true,
+			),
+			&response,
+			nil,
+		)
+		ratelimitData = rl
+		if err != nil {
+			return nil, ratelimitData, fmt.Errorf("error fetching IDP group: %w", err)
+		}
+
+		if group == nil {
+			group = &response
+			group.Members = make([]Member, 0, len(response.Members))
+		}
+
+		added := 0
+		for _, member := range response.Members {
+			if seen[member.Value] {
+				continue
+			}
+			seen[member.Value] = true
+			group.Members = append(group.Members, member)
+			added++
+		}
+
+		if len(response.Members) < SCIMGroupMembersPageSize || added == 0 {
+			return group, ratelimitData, nil
+		}
+		startIndex += len(response.Members)
+	}
+}
+
+// AddUserToGroup patches a group by adding a user to it. It reports whether
+// the user was added, i.e. wasn't a member already.
+func (c *Client) AddUserToGroup(
+	ctx context.Context,
+	groupID string,
+	user string,
+) (
+	bool,
+	*v2.RateLimitDescription,
+	error,
+) {
+	wasAdded, ratelimitData, err := c.patchGroupMembers(
+		ctx,
+		groupID,
+		func(group *GroupResource) *PatchOp {
+			// Adding an existing member fails on some SCIM versions and
+			// duplicates the member entry on others.
+			if isGroupMember(group, user) {
+				return nil
+			}
+
+			return &PatchOp{
+				Schemas: []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+				Operations: []ScimOperate{
+					{
+						Op:   "add",
+						Path: "members",
+						Value: []UserID{
+							{Value: user},
+						},
+					},
+				},
+			}
+		},
+		func(group *GroupResource) bool {
+			return isGroupMember(group, user)
+		},
+	)
+	if err != nil {
+		return false, ratelimitData, fmt.Errorf("error adding user to IDP group: %w", err)
+	}
+
+	return wasAdded, ratelimitData, nil
+}
+
+// RemoveUserFromGroup patches a group by removing a user from it.
+func (c *Client) RemoveUserFromGroup(
+	ctx context.Context,
+	groupID string,
+	user string,
+) (
+	bool,
+	*v2.RateLimitDescription,
+	error,
+) {
+	wasRemoved, ratelimitData, err := c.patchGroupMembers(
+		ctx,
+		groupID,
+		func(group *GroupResource) *PatchOp {
+			// If we don't find the user, we can short-circuit here.
+			if !isGroupMember(group, user) {
+				return nil
+			}
+
+			// Only the user is removed, rather than replacing the members with
+			// the ones fetched, so that a member added concurrently is kept.
+			return &PatchOp{
+				Schemas: []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+				Operations: []ScimOperate{
+					{
+						Op:   "remove",
+						Path: memberFilterPath(user),
+					},
+				},
+			}
+		},
+		func(group *GroupResource) bool {
+			return !isGroupMember(group, user)
+		},
+	)
+	if err != nil {
+		return false, ratelimitData, fmt.Errorf("error removing user from IDP group: %w", err)
+	}
+
+	return wasRemoved, ratelimitData, nil
+}
+
+func isGroupMember(group *GroupResource, user string) bool {
+	for _, member := range group.Members {
+		if member.Value == user {
+			return true
+		}
+	}
+	return false
+}
+
+// patchGroupMembers - membership updates can race other writers, e.g. the IdP
+// pushing its own changes while we provision. Every attempt starts from a
+// freshly fetched group: buildPatch returns the operation to apply (or nil if
+// there is nothing to do) and verify checks that the change actually stuck. A
+// conflicting write or a lost update is retried a bounded number of times.
+func (c *Client) patchGroupMembers(
+	ctx context.Context,
+	groupID string,
+	buildPatch func(group *GroupResource) *PatchOp,
+	verify func(group *GroupResource) bool,
+) (
+	bool,
+	*v2.RateLimitDescription,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	var ratelimitData *v2.RateLimitDescription
+	for attempt := 1; attempt <= MaxGroupPatchAttempts; attempt++ {
+		group, rl, err := c.getIDPGroupUncached(ctx, groupID)
+		ratelimitData = rl
+		if err != nil {
+			return false, ratelimitData, err
+		}
+
+		requestBody := buildPatch(group)
+		if requestBody == nil {
+			return false, ratelimitData, nil
+		}
+
+		ratelimitData, err = c.patchGroup(ctx, groupID, c.encodeGroupPatch(group, *requestBody))
+		if err != nil {
+			if !isSCIMConflict(err) {
+				return false, ratelimitData, err
+			}
+			logger.Debug(
+				"baton-slack: conflict while patching IDP group, retrying",
+				zap.String("group_id", groupID),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		updated, rl, err := c.getIDPGroupUncached(ctx, groupID)
+		ratelimitData = rl
+		if err != nil {
+			return false, ratelimitData, err
+		}
+
+		if verify(updated) {
+			return true, ratelimitData, nil
+		}
+
+		logger.Debug(
+			"baton-slack: IDP group was modified concurrently, retrying",
+			zap.String("group_id", groupID),
+			zap.Int("attempt", attempt),
+		)
+	}
+
+	return false, ratelimitData, fmt.Errorf(
+		"baton-slack: IDP group %s kept changing, giving up after %d attempts",
+		groupID,
+		MaxGroupPatchAttempts,
+	)
+}
+
+func (c *Client) patchGroup(
+	ctx context.Context,
+	groupID string,
+	requestBody interface{},
+) (
+	*v2.RateLimitDescription,
+	error,
+) {
 	var response *GroupResource
 	ratelimitData, err := c.patchScim(
 		ctx,
 		fmt.Sprintf(UrlPathIDPGroup, groupID),
 		&response,
-		payload,
+		requestBody,
 	)
 	if err != nil {
 		return ratelimitData, fmt.Errorf("error patching IDP group: %w", err)
@@ -3,15 +3,19 @@ package connector
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/conductorone/baton-slack/pkg"
 	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/slack-go/slack"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type Slack struct {
@@ -20,13 +24,430 @@ type Slack struct {
 	enterpriseClient *enterprise.Client
 	enterpriseID     string
 	ssoEnabled       bool
+	userOptions      userOptions
+	auditChannelID   string
+	audit            *auditLog
+	failureWebhook   string
+	notifier         *failureNotifier
+	healthAddr       string
+	health           *healthState
+	adminAPIOnly     bool
+	singleWorkspace  bool
+	authTeam         slack.Team
+	checkpointPath   string
+	checkpoint       *checkpointStore
+	cache            *syncCache
+	cacheSize        int
+	pprofAddr        string
+	roleEmission     string
+	govSlack         bool
+	scimVersion      string
+	// scimMaxRetries bounds the retries of rate limited SCIM requests.
+	scimMaxRetries int
+	// maxConcurrency bounds the workspaces whose users are fetched at once.
+	maxConcurrency int
+	// skippedResourceTypes are the IDs of the resource types not to sync.
+	skippedResourceTypes map[string]bool
+	// entitlementsOnlyResourceTypes are the IDs of the resource types synced
+	// without grants.
+	entitlementsOnlyResourceTypes map[string]bool
+	// verifyBeforeWrite checks the current state before a grant or revoke.
+	verifyBeforeWrite bool
+	// guestExpirationDays is how long guest role grants last.
+	guestExpirationDays int
+	// syncStatsAnnotations attaches the counts of every finished sync
+	// operation to its last response.
+	syncStatsAnnotations bool
+	// channelFilter selects channels by name.
+	channelFilter channelFilter
+	// maxChannelMembersForGrants is the member count above which channels
+	// are synced without their member grants.
+	maxChannelMembersForGrants int
+	// userGroupChannelGrants grants channels to the user groups they are a
+	// default channel of.
+	userGroupChannelGrants bool
+	// planTier is the plan of the organization, one of the Plan constants.
+	planTier string
+	// retryQueuePath is where failed provisioning operations are queued.
+	retryQueuePath string
+	retries        *retryQueue
+	// readOnly rejects every operation that would change Slack.
+	readOnly bool
+	// eventsAddr is where Slack Events API requests are received.
+	eventsAddr          string
+	eventsSigningSecret string
+	events              *eventReceiver
+	// auditLogs lists audit log entries in the event feed.
+	auditLogs bool
 }
 
-// Metadata returns metadata about the connector.
+const govSlackAPIURL = "https://slack-gov.com/api/"
+
+// DefaultMaxConcurrency is the number of workspaces whose users are fetched at
+// once unless WithMaxConcurrency says otherwise.
+const DefaultMaxConcurrency = 4
+
+// Option configures optional behavior of the connector.
+type Option func(*Slack)
+
+// WithInactiveDays flags users that haven't accessed Slack in the given number
+// of days as inactive. Zero disables the check.
+func WithInactiveDays(days int) Option {
+	return func(s *Slack) {
+		s.userOptions.inactiveDays = days
+	}
+}
+
+// WithGuestExpirationDays makes a grant of a guest role deactivate the guest
+// after the given number of days, with admin.users.setExpiration. Zero leaves
+// the expiration of the guest unchanged.
+func WithGuestExpirationDays(days int) Option {
+	return func(s *Slack) {
+		s.guestExpirationDays = days
+	}
+}
+
+// WithLastLogin sets the last login of every user, read from the workspace
+// access logs, on the user trait and as `last_login` in the profile. It reads
+// every page of the access logs Slack keeps, instead of stopping at the
+// inactivity cutoff.
+func WithLastLogin(enabled bool) Option {
+	return func(s *Slack) {
+		s.userOptions.syncLastLogin = enabled
+	}
+}
+
+// WithAuditChannel posts a message to the given channel for every provisioning
+// operation performed by the connector.
+func WithAuditChannel(channelID string) Option {
+	return func(s *Slack) {
+		s.auditChannelID = channelID
+	}
+}
+
+// WithFailureWebhook posts a JSON notification to the given URL whenever a
+// provisioning operation fails. Slack incoming webhook URLs are supported.
+func WithFailureWebhook(url string) Option {
+	return func(s *Slack) {
+		s.failureWebhook = url
+	}
+}
+
+// WithHealthAddr serves a health endpoint on the given address, see
+// ServeHealth.
+func WithHealthAddr(addr string) Option {
+	return func(s *Slack) {
+		s.healthAddr = addr
+	}
+}
+
+// WithPprofAddr serves the Go runtime profiles on the given address, see
+// ServePprof. The profiles expose internals of the process, so the address
+// should not be reachable from outside the host.
+func WithPprofAddr(addr string) Option {
+	return func(s *Slack) {
+		s.pprofAddr = addr
+	}
+}
+
+// WithEvents receives user events from the Slack Events API on the given
+// address, see ServeEvents, and verifies them with the signing secret of the
+// Slack app. The events are listed by ListEvents.
+func WithEvents(addr string, signingSecret string) Option {
+	return func(s *Slack) {
+		s.eventsAddr = addr
+		s.eventsSigningSecret = signingSecret
+	}
+}
+
+// WithAuditLogs lists user logins, channel creations, role changes and app
+// installations from the Enterprise Grid audit logs in the event feed. It
+// requires the auditlogs:read scope on the enterprise token.
+func WithAuditLogs(enabled bool) Option {
+	return func(s *Slack) {
+		s.auditLogs = enabled
+	}
+}
+
+// WithAdminAPIOnly syncs from the admin and SCIM APIs with the enterprise
+// token alone, for Enterprise Grid organizations that don't install the bot in
+// every workspace. Users and roles are read from admin.users.list, which
+// doesn't report invitations or organization roles.
+func WithAdminAPIOnly(enabled bool) Option {
+	return func(s *Slack) {
+		s.adminAPIOnly = enabled
+	}
+}
+
+// WithSingleWorkspace syncs only the workspace the bot token belongs to, as
+// reported by auth.test, instead of listing workspaces. Meant for workspaces
+// that are not part of an Enterprise Grid organization.
+func WithSingleWorkspace(enabled bool) Option {
+	return func(s *Slack) {
+		s.singleWorkspace = enabled
+	}
+}
+
+// WithCheckpointFile persists the progress builders keep in memory to the
+// given file, so that a sync resumed after a restart behaves like an
+// uninterrupted one.
+func WithCheckpointFile(path string) Option {
+	return func(s *Slack) {
+		s.checkpointPath = path
+	}
+}
+
+// WithRoleEmission chooses whether users are granted every workspace and
+// organization role that applies to them (RoleEmissionAll) or only the most
+// privileged one (RoleEmissionHighest), e.g. a primary owner is otherwise also
+// granted the owner and admin roles.
+func WithRoleEmission(policy string) Option {
+	return func(s *Slack) {
+		s.roleEmission = policy
+	}
+}
+
+// WithSkippedResourceTypes leaves the resource types with the given IDs, e.g.
+// enterpriseRole or group, out of the sync, trading completeness for sync time
+// and rate limit budget. Users and workspaces can't be skipped as every other
+// resource type depends on them.
+func WithSkippedResourceTypes(resourceTypeIDs []string) Option {
+	return func(s *Slack) {
+		s.skippedResourceTypes = make(map[string]bool, len(resourceTypeIDs))
+		for _, resourceTypeID := range resourceTypeIDs {
+			s.skippedResourceTypes[resourceTypeID] = true
+		}
+	}
+}
+
+// WithEntitlementsOnly syncs the resource types with the given IDs and their
+// entitlements, but not their grants. Meant for a fast first sync of a large
+// organization, before membership sync is enabled.
+func WithEntitlementsOnly(resourceTypeIDs []string) Option {
+	return func(s *Slack) {
+		s.entitlementsOnlyResourceTypes = make(map[string]bool, len(resourceTypeIDs))
+		for _, resourceTypeID := range resourceTypeIDs {
+			s.entitlementsOnlyResourceTypes[resourceTypeID] = true
+		}
+	}
+}
+
+// WithVerifyBeforeWrite makes grants and revokes of workspace roles and
+// authentication policies check the current state first, and skip the write
+// if there is nothing to change. This costs extra API calls, but keeps them
+// idempotent where Slack doesn't report that nothing changed. IDP group
+// memberships are always checked.
+func WithVerifyBeforeWrite(enabled bool) Option {
+	return func(s *Slack) {
+		s.verifyBeforeWrite = enabled
+	}
+}
+
+// WithGovSlack connects to GovSlack instead of the commercial Slack API.
+func WithGovSlack(enabled bool) Option {
+	return func(s *Slack) {
+		s.govSlack = enabled
+	}
+}
+
+// WithSCIMVersion overrides the SCIM API version used for IDP groups. By
+// default GovSlack uses v1 and everything else v2.
+func WithSCIMVersion(version string) Option {
+	return func(s *Slack) {
+		s.scimVersion = version
+	}
+}
+
+// WithSCIMMaxRetries bounds how many times a rate limited SCIM request is
+// retried after waiting as long as Slack asks, before the operation fails.
+// Zero disables the retries.
+func WithSCIMMaxRetries(retries int) Option {
+	return func(s *Slack) {
+		s.scimMaxRetries = retries
+	}
+}
+
+// WithMaxConcurrency sets how many workspaces have their users fetched at once
+// while syncing workspace grants. Requests are still held to their rate limit
+// tier. One fetches a workspace at a time.
+func WithMaxConcurrency(n int) Option {
+	return func(s *Slack) {
+		s.maxConcurrency = n
+	}
+}
+
+// WithSyncStatsAnnotations attaches the counts logged at the end of every sync
+// operation to the last response of the operation as an annotation.
+func WithSyncStatsAnnotations(enabled bool) Option {
+	return func(s *Slack) {
+		s.syncStatsAnnotations = enabled
+	}
+}
+
+// WithCacheSize bounds the number of entries each in-memory cache keeps, e.g.
+// pages of admin users, IDP groups or user presences. Zero uses the default.
+func WithCacheSize(size int) Option {
+	return func(s *Slack) {
+		s.cacheSize = size
+	}
+}
+
+// WithPresence adds the current presence of every user to their profile. This
+// costs one extra API call per user.
+func WithPresence(enabled bool) Option {
+	return func(s *Slack) {
+		s.userOptions.syncPresence = enabled
+	}
+}
+
+// WithEmployeeNumbers adds the employee number from the SCIM enterprise
+// extension to every user profile. It requires SSO since it reads SCIM users.
+func WithEmployeeNumbers(enabled bool) Option {
+	return func(s *Slack) {
+		s.userOptions.syncEmployeeNumbers = enabled
+	}
+}
+
+// WithAnalytics adds the messages posted, the days active and the last active
+// day of the last week, read from the member analytics files, to every user
+// profile. It requires an Enterprise Grid organization.
+func WithAnalytics(enabled bool) Option {
+	return func(s *Slack) {
+		s.userOptions.syncAnalytics = enabled
+	}
+}
+
+// WithReadOnly rejects every grant, revoke, account creation and action that
+// would change Slack, regardless of whether provisioning is enabled.
+func WithReadOnly(enabled bool) Option {
+	return func(s *Slack) {
+		s.readOnly = enabled
+	}
+}
+
+// WithRetryQueueFile queues grants and revokes that fail with a transient
+// error in the given file, and retries them from RunRetryQueue.
+func WithRetryQueueFile(path string) Option {
+	return func(s *Slack) {
+		s.retryQueuePath = path
+	}
+}
+
+// WithBotOwners adds the app every bot user belongs to, and the admin who
+// approved it, to the bot's profile. It requires Enterprise Grid since it reads
+// the app approvals of the organization.
+func WithBotOwners(enabled bool) Option {
+	return func(s *Slack) {
+		s.userOptions.resolveBotOwners = enabled
+	}
+}
+
+// WithExcludedProfileFields omits the given keys from synced user profiles,
+// for deployments with data minimization requirements.
+func WithExcludedProfileFields(fields []string) Option {
+	return func(s *Slack) {
+		s.userOptions.excludedProfileFields = make(map[string]bool, len(fields))
+		for _, field := range fields {
+			s.userOptions.excludedProfileFields[field] = true
+		}
+	}
+}
+
+// WithEmailNormalization normalizes synced user emails so that they match the
+// ones held by the IdP: lowercasing them, stripping "+tag" plus addressing and
+// rewriting alias domains to their canonical domain.
+func WithEmailNormalization(lowercase, stripPlusAddressing bool, domainAliases map[string]string) Option {
+	return func(s *Slack) {
+		s.userOptions.emailNormalization = emailNormalization{
+			lowercase:           lowercase,
+			stripPlusAddressing: stripPlusAddressing,
+			domainAliases:       domainAliases,
+		}
+	}
+}
+
+// WithExternalUsers syncs the users of other organizations that Slack lists
+// in a workspace, e.g. members of channels shared through Slack Connect. They
+// are tagged with an external account type in their profile. By default they
+// are left out.
+func WithExternalUsers(enabled bool) Option {
+	return func(s *Slack) {
+		s.userOptions.includeExternalUsers = enabled
+	}
+}
+
+// WithMissingEmail chooses how users without an email are synced, one of
+// MissingEmailKeep, MissingEmailSkip, MissingEmailOmit or
+// MissingEmailPlaceholder.
+func WithMissingEmail(mode string) Option {
+	return func(s *Slack) {
+		s.userOptions.missingEmail = mode
+	}
+}
+
+// WithServiceAccountPatterns marks users whose name or email matches any of
+// the given regular expressions as service accounts.
+func WithServiceAccountPatterns(patterns []*regexp.Regexp) Option {
+	return func(s *Slack) {
+		s.userOptions.serviceAccountPatterns = patterns
+	}
+}
+
+// Plan tiers, as far as they can be told apart from the configured tokens.
+const (
+	PlanEnterpriseGrid = "enterprise_grid"
+	PlanBusinessPlus   = "business_plus"
+	PlanStandard       = "standard"
+)
+
+// WithChannelFilters selects the channels the connector reads by name. A
+// channel is read if it matches any include pattern, or if there are none, and
+// doesn't match any exclude pattern.
+func WithChannelFilters(include []*regexp.Regexp, exclude []*regexp.Regexp) Option {
+	return func(s *Slack) {
+		s.channelFilter = channelFilter{include: include, exclude: exclude}
+	}
+}
+
+// WithMaxChannelMembersForGrants syncs channels with more than the given number
+// of members without their member grants, with the member count in their
+// profile, so that a handful of organization-wide channels don't take hours to
+// sync. Zero syncs the grants of every channel.
+func WithMaxChannelMembersForGrants(n int) Option {
+	return func(s *Slack) {
+		s.maxChannelMembersForGrants = n
+	}
+}
+
+// WithUserGroupChannelGrants grants a channel to the user groups it is a
+// default channel of, expanded to their members, instead of granting it to
+// each of those members directly. A user group is only granted the channel
+// while all of its members are in it.
+func WithUserGroupChannelGrants(enabled bool) Option {
+	return func(s *Slack) {
+		s.userGroupChannelGrants = enabled
+	}
+}
+
+// Metadata returns metadata about the connector. The profile reports the mode
+// the connector runs in so that operators can check it at a glance.
 func (c *Slack) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
+	profile, err := structpb.NewStruct(map[string]interface{}{
+		"plan_tier":     c.planTier,
+		"enterprise_id": c.enterpriseID,
+		"sso_enabled":   c.ssoEnabled,
+		"scim_version":  c.enterpriseClient.SCIMVersion(),
+		"govslack":      c.govSlack,
+		"read_only":     c.readOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &v2.ConnectorMetadata{
 		DisplayName: "Slack",
 		Description: "Connector syncing users, workspaces, user groups and workspace roles from Slack to Baton.",
+		Profile:     profile,
 	}, nil
 }
 
@@ -34,12 +455,12 @@ func (c *Slack) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
 func (s *Slack) Validate(ctx context.Context) (annotations.Annotations, error) {
 	res, err := s.client.AuthTestContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("slack-connector: failed to authenticate. Error: %w", err)
+		return nil, fmt.Errorf("slack-connector: failed to authenticate. Error: %w", pkg.WrapTokenError(err))
 	}
 
 	user, err := s.client.GetUserInfoContext(ctx, res.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("slack-connector: failed to retrieve authenticated user. Error: %w", err)
+		return nil, fmt.Errorf("slack-connector: failed to retrieve authenticated user. Error: %w", pkg.WrapTokenError(err))
 	}
 
 	isValidUser := user.IsAdmin || user.IsOwner || user.IsPrimaryOwner || user.IsBot
@@ -60,24 +481,75 @@ func (s *slackLogger) Output(callDepth int, msg string) error {
 }
 
 // New returns the Slack connector.
-func New(ctx context.Context, apiKey, enterpriseKey string, ssoEnabled bool) (*Slack, error) {
+func New(ctx context.Context, apiKey, enterpriseKey string, ssoEnabled bool, opts ...Option) (*Slack, error) {
 	l := ctxzap.Extract(ctx)
 	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, l))
 	if err != nil {
 		return nil, err
 	}
 
+	s := &Slack{
+		ssoEnabled:     ssoEnabled,
+		scimMaxRetries: enterprise.DefaultSCIMMaxRetries,
+		maxConcurrency: DefaultMaxConcurrency,
+	}
+	withSharedTransport(httpClient, newRateLimiter())
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	switch s.roleEmission {
+	case "", RoleEmissionAll, RoleEmissionHighest:
+	default:
+		return nil, fmt.Errorf("slack-connector: invalid role emission policy %q", s.roleEmission)
+	}
+
+	if s.scimMaxRetries < 0 {
+		return nil, fmt.Errorf("slack-connector: invalid SCIM retry count %d", s.scimMaxRetries)
+	}
+
+	if s.guestExpirationDays < 0 {
+		return nil, fmt.Errorf("slack-connector: invalid guest expiration %d", s.guestExpirationDays)
+	}
+
+	if s.maxChannelMembersForGrants < 0 {
+		return nil, fmt.Errorf("slack-connector: invalid max channel members for grants %d", s.maxChannelMembersForGrants)
+	}
+
+	if s.maxConcurrency < 1 {
+		return nil, fmt.Errorf("slack-connector: invalid max concurrency %d", s.maxConcurrency)
+	}
+
+	switch s.userOptions.missingEmail {
+	case "", MissingEmailKeep, MissingEmailSkip, MissingEmailOmit, MissingEmailPlaceholder:
+	default:
+		return nil, fmt.Errorf("slack-connector: invalid missing email handling %q", s.userOptions.missingEmail)
+	}
+
+	// Without a bot installed, the Business+ token is used for every call.
+	if s.adminAPIOnly {
+		if enterpriseKey == "" {
+			return nil, fmt.Errorf("slack-connector: admin API only mode requires an enterprise token")
+		}
+		apiKey = enterpriseKey
+	} else if apiKey == "" {
+		return nil, fmt.Errorf("slack-connector: a bot token is required unless admin API only mode is enabled")
+	}
+
 	logger := &slackLogger{ZapLog: l}
-	opts := []slack.Option{
+	slackOptions := []slack.Option{
 		slack.OptionDebug(true),
 		slack.OptionHTTPClient(httpClient),
 		slack.OptionLog(logger),
 	}
-	client := slack.New(apiKey, opts...)
+	if s.govSlack {
+		slackOptions = append(slackOptions, slack.OptionAPIURL(govSlackAPIURL))
+	}
+	client := slack.New(apiKey, slackOptions...)
 
 	res, err := client.AuthTestContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("slack-connector: failed to authenticate. Error: %w", err)
+		return nil, fmt.Errorf("slack-connector: failed to authenticate. Error: %w", pkg.WrapTokenError(err))
 	}
 
 	var enterpriseId string
@@ -87,33 +559,176 @@ func New(ctx context.Context, apiKey, enterpriseKey string, ssoEnabled bool) (*S
 			return nil, fmt.Errorf("slack-connector: enterprise account detected, but no enterprise token specified")
 		}
 	}
+	if s.adminAPIOnly && enterpriseId == "" {
+		return nil, fmt.Errorf("slack-connector: admin API only mode requires an Enterprise Grid organization")
+	}
+	if s.singleWorkspace && enterpriseId != "" {
+		return nil, fmt.Errorf("slack-connector: single workspace mode is not available for Enterprise Grid organizations")
+	}
 	enterpriseClient, err := enterprise.NewClient(
 		httpClient,
 		enterpriseKey,
 		apiKey,
 		res.EnterpriseID,
 		ssoEnabled,
+		enterprise.WithGovSlack(s.govSlack),
+		enterprise.WithSCIMVersion(s.scimVersion),
+		enterprise.WithSCIMMaxRetries(s.scimMaxRetries),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("slack-connector: failed to create enterprise client. Error: %w", err)
 	}
 
-	return &Slack{
-		client:           client,
-		apiKey:           apiKey,
-		enterpriseClient: enterpriseClient,
-		enterpriseID:     enterpriseId,
-		ssoEnabled:       ssoEnabled,
-	}, nil
+	s.client = client
+	s.apiKey = apiKey
+	s.enterpriseClient = enterpriseClient
+	s.enterpriseID = enterpriseId
+	s.authTeam = slack.Team{ID: res.TeamID, Name: res.Team}
+	switch {
+	case enterpriseId != "":
+		s.planTier = PlanEnterpriseGrid
+	case enterpriseKey != "":
+		s.planTier = PlanBusinessPlus
+	default:
+		s.planTier = PlanStandard
+	}
+
+	if s.auditChannelID != "" {
+		s.audit = &auditLog{
+			client:    client,
+			channelID: s.auditChannelID,
+			actorID:   res.UserID,
+		}
+	}
+
+	if s.failureWebhook != "" {
+		s.notifier = &failureNotifier{
+			httpClient: httpClient,
+			webhookURL: s.failureWebhook,
+		}
+	}
+
+	if s.healthAddr != "" {
+		s.health = &healthState{
+			client: client,
+			addr:   s.healthAddr,
+		}
+	}
+
+	if s.eventsAddr != "" {
+		if s.eventsSigningSecret == "" {
+			return nil, fmt.Errorf("slack-connector: receiving events requires the signing secret of the Slack app")
+		}
+		s.events = newEventReceiver(s.eventsAddr, s.eventsSigningSecret)
+	}
+
+	s.checkpoint, err = loadCheckpointStore(s.checkpointPath)
+	if err != nil {
+		return nil, err
+	}
+	s.retries, err = loadRetryQueue(s.retryQueuePath)
+	if err != nil {
+		return nil, err
+	}
+	s.cache = newSyncCache(s.cacheSize)
+	if _, err := s.checkpoint.load(checkpointWorkspaceNames, &s.cache.workspaceNames); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateResourceTypes(ctx); err != nil {
+		return nil, err
+	}
+
+	if s.userOptions.syncEmployeeNumbers && !ssoEnabled {
+		return nil, fmt.Errorf("slack-connector: syncing employee numbers requires SSO to be enabled")
+	}
+
+	if s.userOptions.syncAnalytics && enterpriseId == "" {
+		return nil, fmt.Errorf("slack-connector: syncing member analytics requires an Enterprise Grid organization")
+	}
+
+	if s.guestExpirationDays > 0 && enterpriseId == "" {
+		return nil, fmt.Errorf("slack-connector: guest expiration requires an Enterprise Grid organization")
+	}
+
+	if s.auditLogs && enterpriseId == "" {
+		return nil, fmt.Errorf("slack-connector: reading audit logs requires an Enterprise Grid organization")
+	}
+
+	if s.userOptions.resolveBotOwners && enterpriseId == "" {
+		return nil, fmt.Errorf("slack-connector: resolving bot owners requires an Enterprise Grid organization")
+	}
+
+	// Access logs are only available to a user token with the admin scope.
+	if s.userOptions.inactiveDays > 0 && enterpriseKey == "" {
+		return nil, fmt.Errorf("slack-connector: inactive user detection requires an enterprise token")
+	}
+	if s.userOptions.syncLastLogin && enterpriseKey == "" {
+		return nil, fmt.Errorf("slack-connector: syncing last logins requires an enterprise token")
+	}
+
+	return s, nil
 }
 
 func (s *Slack) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
+	syncers := s.allResourceSyncers()
+	if len(s.skippedResourceTypes) == 0 {
+		return syncers
+	}
+
+	rv := make([]connectorbuilder.ResourceSyncer, 0, len(syncers))
+	for _, syncer := range syncers {
+		if !s.skippedResourceTypes[syncer.ResourceType(ctx).Id] {
+			rv = append(rv, syncer)
+		}
+	}
+	return rv
+}
+
+func (s *Slack) allResourceSyncers() []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
-		userBuilder(s.client, s.enterpriseID, s.enterpriseClient),
-		workspaceBuilder(s.client, s.enterpriseID, s.enterpriseClient),
-		userGroupBuilder(s.client, s.enterpriseID, s.enterpriseClient),
-		workspaceRoleBuilder(s.client, s.enterpriseClient),
+		userBuilder(s.client, s.enterpriseID, s.enterpriseClient, s.userOptions, s.adminAPIOnly, s.ssoEnabled, s.cache),
+		workspaceBuilder(s.client, s.enterpriseID, s.enterpriseClient, s.adminAPIOnly, s.workspaceScope(), s.checkpoint, s.cache, s.roleEmission == RoleEmissionHighest, s.maxConcurrency),
+		userGroupBuilder(s.client, s.enterpriseID, s.enterpriseClient, s.checkpoint, s.cache),
+		workspaceRoleBuilder(s.client, s.enterpriseClient, s.cache, s.ssoEnabled, s.verifyBeforeWrite, time.Duration(s.guestExpirationDays)*24*time.Hour),
 		enterpriseRoleBuilder(s.enterpriseID, s.enterpriseClient),
-		groupBuilder(s.enterpriseClient, s.enterpriseID, s.ssoEnabled),
+		groupBuilder(s.enterpriseClient, s.enterpriseID, s.ssoEnabled, s.cache),
+		authPolicyBuilder(s.enterpriseID, s.enterpriseClient, s.verifyBeforeWrite),
+		externalOrganizationBuilder(s.client, s.channelFilter, s.cache),
+		channelBuilder(s.client, s.enterpriseID, s.enterpriseClient, s.cache, s.channelFilter, s.maxChannelMembersForGrants, s.userGroupChannelGrants),
+		sharedChannelBuilder(s.client, s.enterpriseID, s.enterpriseClient, s.channelFilter, s.cache),
+		appBuilder(s.enterpriseID, s.enterpriseClient, s.checkpoint),
+	}
+}
+
+func (s *Slack) validateResourceTypes(ctx context.Context) error {
+	known := make(map[string]bool)
+	for _, syncer := range s.allResourceSyncers() {
+		known[syncer.ResourceType(ctx).Id] = true
+	}
+
+	for resourceTypeID := range s.skippedResourceTypes {
+		switch {
+		case !known[resourceTypeID]:
+			return fmt.Errorf("slack-connector: unknown resource type %q", resourceTypeID)
+		case resourceTypeID == resourceTypeUser.Id || resourceTypeID == resourceTypeWorkspace.Id:
+			return fmt.Errorf("slack-connector: the %s resource type can't be skipped", resourceTypeID)
+		}
+	}
+
+	for resourceTypeID := range s.entitlementsOnlyResourceTypes {
+		if !known[resourceTypeID] {
+			return fmt.Errorf("slack-connector: unknown resource type %q", resourceTypeID)
+		}
+	}
+	return nil
+}
+
+// workspaceScope returns the only workspace to sync, or nil to list them.
+func (s *Slack) workspaceScope() *slack.Team {
+	if !s.singleWorkspace {
+		return nil
 	}
+	team := s.authTeam
+	return &team
 }
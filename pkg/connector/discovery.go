@@ -0,0 +1,142 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/conductorone/baton-slack/pkg"
+	"github.com/slack-go/slack"
+)
+
+// WorkspaceAccess describes a discovered workspace and which of the
+// configured tokens can reach it.
+type WorkspaceAccess struct {
+	ID   string
+	Name string
+	// MemberCount is -1 when the bot token can't list the workspace members.
+	MemberCount int
+	BotAccess   bool
+	AdminAccess bool
+}
+
+// DiscoverWorkspaces lists every workspace visible to either token. It is
+// meant to help scope a deployment, so it reads everything eagerly and
+// doesn't go through the sync machinery.
+func (s *Slack) DiscoverWorkspaces(ctx context.Context) ([]WorkspaceAccess, error) {
+	found := make(map[string]*WorkspaceAccess)
+	get := func(team slack.Team) *WorkspaceAccess {
+		workspace, ok := found[team.ID]
+		if !ok {
+			workspace = &WorkspaceAccess{ID: team.ID, Name: team.Name, MemberCount: -1}
+			found[team.ID] = workspace
+		}
+		return workspace
+	}
+
+	// auth.teams.list returns the workspaces the bot is installed in.
+	err := pkg.ForEachPage("workspaces", func(cursor string) (string, error) {
+		teams, nextCursor, err := s.client.ListTeamsContext(ctx, slack.ListTeamsParameters{Cursor: cursor})
+		if err != nil {
+			return "", fmt.Errorf("baton-slack: error listing workspaces with the bot token: %w", pkg.WrapTokenError(err))
+		}
+		for _, team := range teams {
+			get(team).BotAccess = true
+		}
+		return nextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.enterpriseID != "" {
+		err := pkg.ForEachPage("workspaces", func(cursor string) (string, error) {
+			teams, nextCursor, _, err := s.enterpriseClient.GetTeams(ctx, cursor)
+			if err != nil {
+				return "", fmt.Errorf("baton-slack: error listing workspaces with the enterprise token: %w", err)
+			}
+			for _, team := range teams {
+				get(team).AdminAccess = true
+			}
+			return nextCursor, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, workspace := range found {
+		if !workspace.BotAccess {
+			continue
+		}
+		count, err := s.countMembers(ctx, workspace.ID)
+		if err != nil {
+			// The bot may be listed without being able to read users, which is
+			// exactly what this diagnostic should surface.
+			workspace.BotAccess = false
+			continue
+		}
+		workspace.MemberCount = count
+	}
+
+	rv := make([]WorkspaceAccess, 0, len(found))
+	for _, workspace := range found {
+		rv = append(rv, *workspace)
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].Name < rv[j].Name
+	})
+	return rv, nil
+}
+
+func (s *Slack) countMembers(ctx context.Context, teamID string) (int, error) {
+	count := 0
+	err := pkg.ForEachPage("users", func(cursor string) (string, error) {
+		users, nextCursor, _, err := s.enterpriseClient.GetUsers(ctx, teamID, cursor)
+		if err != nil {
+			return "", err
+		}
+		for _, user := range users {
+			if !user.Deleted && !user.IsStranger {
+				count++
+			}
+		}
+		return nextCursor, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// PrintWorkspaces writes the discovered workspaces as a table.
+func PrintWorkspaces(w io.Writer, workspaces []WorkspaceAccess) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tMEMBERS\tBOT TOKEN\tADMIN TOKEN")
+	for _, workspace := range workspaces {
+		members := "-"
+		if workspace.MemberCount >= 0 {
+			members = strconv.Itoa(workspace.MemberCount)
+		}
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%s\n",
+			workspace.ID,
+			workspace.Name,
+			members,
+			yesNo(workspace.BotAccess),
+			yesNo(workspace.AdminAccess),
+		)
+	}
+	return tw.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,65 @@
+package connector
+
+import (
+	"context"
+	"sort"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// emailIndex remembers which active users were seen with each normalized
+// email during the sync. Several active accounts sharing an email usually
+// means an orphaned duplicate account.
+type emailIndex map[string]map[string]bool
+
+// add records the users and returns, for every given user whose email is
+// shared, the other users seen with it so far. Each new conflict is logged.
+func (idx emailIndex) add(ctx context.Context, emails map[string]string) map[string][]string {
+	logger := ctxzap.Extract(ctx)
+
+	duplicates := make(map[string][]string)
+	for userID, email := range emails {
+		if email == "" {
+			continue
+		}
+
+		userIDs, ok := idx[email]
+		if !ok {
+			userIDs = make(map[string]bool)
+			idx[email] = userIDs
+		}
+		if !userIDs[userID] {
+			userIDs[userID] = true
+			if len(userIDs) > 1 {
+				logger.Warn(
+					"baton-slack: multiple active users share an email",
+					zap.String("email", email),
+					zap.Strings("user_ids", sortedKeys(userIDs)),
+				)
+			}
+		}
+	}
+
+	for userID, email := range emails {
+		if len(idx[email]) < 2 {
+			continue
+		}
+		for otherID := range idx[email] {
+			if otherID != userID {
+				duplicates[userID] = append(duplicates[userID], otherID)
+			}
+		}
+		sort.Strings(duplicates[userID])
+	}
+	return duplicates
+}
+
+func sortedKeys(m map[string]bool) []string {
+	rv := make([]string, 0, len(m))
+	for key := range m {
+		rv = append(rv, key)
+	}
+	sort.Strings(rv)
+	return rv
+}
@@ -0,0 +1,117 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// How users without an email, typically bots and some legacy accounts, are
+// synced.
+const (
+	// MissingEmailKeep emits the user with an empty email.
+	MissingEmailKeep = "keep"
+	// MissingEmailSkip doesn't emit the user at all.
+	MissingEmailSkip = "skip"
+	// MissingEmailOmit emits the user without the email trait.
+	MissingEmailOmit = "omit"
+	// MissingEmailPlaceholder emits the user with a made up email that can't
+	// collide with a real one.
+	MissingEmailPlaceholder = "placeholder"
+)
+
+// placeholderEmailDomain uses the reserved .invalid TLD so that placeholder
+// emails are never deliverable.
+const placeholderEmailDomain = "users.slack.invalid"
+
+// emailNormalization makes emails comparable with the ones the IdP holds for
+// the same person. The zero value leaves emails untouched.
+type emailNormalization struct {
+	lowercase bool
+	// stripPlusAddressing drops the "+tag" part of the local part.
+	stripPlusAddressing bool
+	// domainAliases maps alias domains to the canonical one, lowercased.
+	domainAliases map[string]string
+}
+
+func (n *emailNormalization) normalize(email string) string {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+
+	local, domain := email[:at], email[at+1:]
+	if n.lowercase {
+		local = strings.ToLower(local)
+		domain = strings.ToLower(domain)
+	}
+	if n.stripPlusAddressing {
+		if plus := strings.Index(local, "+"); plus > 0 {
+			local = local[:plus]
+		}
+	}
+	if canonical, ok := n.domainAliases[strings.ToLower(domain)]; ok {
+		domain = canonical
+	}
+	return local + "@" + domain
+}
+
+// ParseDomainAliases parses "alias.com=canonical.com" pairs.
+func ParseDomainAliases(pairs []string) (map[string]string, error) {
+	aliases := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		alias, canonical, ok := strings.Cut(pair, "=")
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		canonical = strings.ToLower(strings.TrimSpace(canonical))
+		if !ok || alias == "" || canonical == "" {
+			return nil, fmt.Errorf("invalid domain alias %q, expected alias.com=canonical.com", pair)
+		}
+		aliases[alias] = canonical
+	}
+	return aliases, nil
+}
+
+// skipUser reports whether the user has to be left out because it has no
+// email.
+func (u *userOptions) skipUser(ctx context.Context, userID string, email string) bool {
+	if u == nil || u.missingEmail != MissingEmailSkip || strings.TrimSpace(email) != "" {
+		return false
+	}
+
+	ctxzap.Extract(ctx).Info(
+		"baton-slack: skipping user without an email",
+		zap.String("user_id", userID),
+	)
+	return true
+}
+
+// resolveEmail returns the email to sync for the user and whether the email
+// trait has to be set at all.
+func (u *userOptions) resolveEmail(ctx context.Context, userID string, email string) (string, bool) {
+	if email != "" || u == nil {
+		return email, true
+	}
+
+	switch u.missingEmail {
+	case MissingEmailOmit:
+		ctxzap.Extract(ctx).Info(
+			"baton-slack: omitting the email of a user without one",
+			zap.String("user_id", userID),
+		)
+		return "", false
+	case MissingEmailPlaceholder:
+		placeholder := strings.ToLower(userID) + "@" + placeholderEmailDomain
+		ctxzap.Extract(ctx).Info(
+			"baton-slack: using a placeholder email for a user without one",
+			zap.String("user_id", userID),
+			zap.String("email", placeholder),
+		)
+		return placeholder, true
+	default:
+		return "", true
+	}
+}
@@ -0,0 +1,58 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-slack/pkg"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+)
+
+// scimUsersPageSize is the largest page the SCIM API returns.
+const scimUsersPageSize = 1000
+
+// fetchEmployeeNumbers reads the employee number of every user from the
+// enterprise extension of the SCIM users. Slack doesn't expose it through the
+// Web API.
+func fetchEmployeeNumbers(
+	ctx context.Context,
+	client *enterprise.Client,
+) (
+	map[string]string,
+	*v2.RateLimitDescription,
+	error,
+) {
+	employeeNumbers := make(map[string]string)
+
+	var ratelimitData *v2.RateLimitDescription
+	startIndex := StartingOffset
+	for page := 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, ratelimitData, err
+		}
+		if page > enterprise.SCIMMaxPages {
+			return nil, ratelimitData, fmt.Errorf(
+				"baton-slack: listing IDP users exceeded %d pages, the SCIM API keeps reporting more results",
+				enterprise.SCIMMaxPages,
+			)
+		}
+
+		response, rl, err := client.ListIDPUsers(ctx, startIndex, scimUsersPageSize)
+		ratelimitData = rl
+		if err != nil {
+			return nil, ratelimitData, err
+		}
+
+		for _, user := range response.Resources {
+			if employeeNumber := user.EmployeeNumber(); employeeNumber != "" {
+				employeeNumbers[user.ID] = employeeNumber
+			}
+		}
+
+		if len(response.Resources) == 0 || pkg.NextOffsetToken(startIndex, scimUsersPageSize, response.TotalResults) == "" {
+			return employeeNumbers, ratelimitData, nil
+		}
+		startIndex += scimUsersPageSize
+	}
+}
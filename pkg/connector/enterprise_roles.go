@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -12,6 +13,8 @@ import (
 	resources "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/conductorone/baton-slack/pkg"
 	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
 const (
@@ -39,6 +42,14 @@ const (
 	OrganizationAdminID        = "organization_admin"
 )
 
+// organizationRolePriority orders organization roles from the most to the
+// least privileged.
+var organizationRolePriority = []string{
+	OrganizationPrimaryOwnerID,
+	OrganizationOwnerID,
+	OrganizationAdminID,
+}
+
 var systemRoles = map[string]string{
 	AnalyticsAdmin:         "Analytics Admin",
 	AuditLogsAdmin:         "Audit Logs Admin",
@@ -82,22 +93,25 @@ func enterpriseRoleBuilder(enterpriseID string, enterpriseClient *enterprise.Cli
 	}
 }
 
+// enterpriseRoleName returns the name of a known system or organization role.
+func enterpriseRoleName(roleID string) (string, bool) {
+	if name, ok := systemRoles[roleID]; ok {
+		return name, true
+	}
+	name, ok := organizationRoles[roleID]
+	return name, ok
+}
+
 func enterpriseRoleResource(
 	_ context.Context,
 	roleID string,
 	_ *v2.ResourceId,
 ) (*v2.Resource, error) {
-	var roleName string
-	systemRoleName, ok := systemRoles[roleID]
+	roleName, ok := enterpriseRoleName(roleID)
 	if !ok {
-		orgRoleName, ok := organizationRoles[roleID]
-		if !ok {
-			return nil, fmt.Errorf("invalid system or organization roleID: %s", roleID)
-		} else {
-			roleName = orgRoleName
-		}
-	} else {
-		roleName = systemRoleName
+		// Roles added by Slack after this list was written are named by their
+		// ID, so that their assignments still show up.
+		roleName = roleID
 	}
 
 	return resources.NewRoleResource(
@@ -148,6 +162,9 @@ func (o *enterpriseRoleType) List(
 		return nil, "", outputAnnotations, err
 	}
 
+	if err := pkg.CheckCursor("role assignments", bag.Cursor, nextPage); err != nil {
+		return nil, "", outputAnnotations, err
+	}
 	bag.Cursor = nextPage
 
 	for _, roleAssignment := range roleAssignments {
@@ -155,8 +172,11 @@ func (o *enterpriseRoleType) List(
 			continue
 		}
 
-		if _, ok := systemRoles[roleAssignment.RoleID]; !ok {
-			continue
+		if _, ok := enterpriseRoleName(roleAssignment.RoleID); !ok {
+			ctxzap.Extract(ctx).Warn(
+				"baton-slack: unknown enterprise role, naming it by its ID",
+				zap.String("role_id", roleAssignment.RoleID),
+			)
 		}
 
 		r, err := enterpriseRoleResource(ctx, roleAssignment.RoleID, parentResourceID)
@@ -191,7 +211,7 @@ func (o *enterpriseRoleType) Entitlements(
 			entitlement.NewAssignmentEntitlement(
 				resource,
 				RoleAssignmentEntitlement,
-				entitlement.WithGrantableTo(resourceTypeUser),
+				entitlement.WithGrantableTo(resourceTypeUser, resourceTypeUserGroup),
 				entitlement.WithDescription(
 					fmt.Sprintf(
 						"Has the %s role in the Slack enterprise",
@@ -245,19 +265,139 @@ func (o *enterpriseRoleType) Grants(
 		return nil, "", outputAnnotations, err
 	}
 
-	pageToken, err := bag.NextToken(nextPage)
+	pageToken, err := pkg.NextPageToken(bag, "role assignments", nextPage)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, "", outputAnnotations, err
 	}
 
 	for _, assignment := range roleAssignments {
+		// Roles can be assigned to a user group as a whole, in which case the
+		// group is the principal and its members get the role via expansion.
+		if isUserGroupID(assignment.UserID) {
+			userGroupID, err := resources.NewResourceID(resourceTypeUserGroup, assignment.UserID)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("failed to create resourceID for user group: %w", err)
+			}
+
+			rv = append(
+				rv,
+				grant.NewGrant(
+					resource,
+					RoleAssignmentEntitlement,
+					userGroupID,
+					withGrantSource(grantSourceRoleAssignments),
+					grant.WithAnnotation(
+						&v2.GrantExpandable{
+							EntitlementIds: []string{
+								fmt.Sprintf("%s:%s:%s", resourceTypeUserGroup.Id, assignment.UserID, memberEntitlement),
+							},
+						},
+					),
+				),
+			)
+			continue
+		}
+
 		userID, err := resources.NewResourceID(resourceTypeUser, assignment.UserID)
 		if err != nil {
 			return nil, "", nil, fmt.Errorf("failed to create resourceID for user: %w", err)
 		}
 
-		rv = append(rv, grant.NewGrant(resource, RoleAssignmentEntitlement, userID))
+		rv = append(
+			rv,
+			grant.NewGrant(resource, RoleAssignmentEntitlement, userID, withGrantSource(grantSourceRoleAssignments)),
+		)
 	}
 
 	return rv, pageToken, outputAnnotations, nil
 }
+
+// isUserGroupID reports whether the ID is the ID of a user group rather than
+// of a user. Slack user group IDs start with S, user IDs with U or W.
+func isUserGroupID(id string) bool {
+	return strings.HasPrefix(id, "S")
+}
+
+// Grant assigns a system role with admin.roles.addAssignments. The grant has
+// no scope of its own, so the role is assigned for the whole organization,
+// which covers every workspace. Organization roles are derived from user flags
+// and can't be granted.
+func (o *enterpriseRoleType) Grant(
+	ctx context.Context,
+	principal *v2.Resource,
+	entitlement *v2.Entitlement,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		logger.Warn(
+			"baton-slack: only users can be assigned an enterprise role",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: only users can be assigned an enterprise role")
+	}
+
+	roleID := entitlement.Resource.Id.Resource
+	if _, ok := organizationRoles[roleID]; ok {
+		return nil, fmt.Errorf("baton-slack: organization role %s can't be granted", roleID)
+	}
+
+	outputAnnotations := annotations.New()
+	ratelimitData, err := o.enterpriseClient.AddRoleAssignment(ctx, roleID, o.enterpriseID, principal.Id.Resource)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	return grantResult(outputAnnotations, err, "failed to assign enterprise role")
+}
+
+// Revoke removes every assignment of a system role to the principal, whether
+// it is scoped to the organization, a workspace or a channel, with
+// admin.roles.removeAssignments.
+func (o *enterpriseRoleType) Revoke(
+	ctx context.Context,
+	grant *v2.Grant,
+) (
+	annotations.Annotations,
+	error,
+) {
+	principal := grant.Principal
+	roleID := grant.Entitlement.Resource.Id.Resource
+	if _, ok := organizationRoles[roleID]; ok {
+		return nil, fmt.Errorf("baton-slack: organization role %s can't be revoked", roleID)
+	}
+
+	outputAnnotations := annotations.New()
+	var entityIDs []string
+	err := pkg.ForEachPage("role assignments", func(cursor string) (string, error) {
+		roleAssignments, nextCursor, ratelimitData, err := o.enterpriseClient.GetRoleAssignments(ctx, roleID, cursor)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return "", err
+		}
+		for _, roleAssignment := range roleAssignments {
+			if roleAssignment.UserID == principal.Id.Resource {
+				entityIDs = append(entityIDs, roleAssignment.EntityID)
+			}
+		}
+		return nextCursor, nil
+	})
+	if err != nil {
+		return outputAnnotations, fmt.Errorf("baton-slack: failed to remove enterprise role: %w", err)
+	}
+
+	if len(entityIDs) == 0 {
+		outputAnnotations.Append(&v2.GrantAlreadyRevoked{})
+		return outputAnnotations, nil
+	}
+
+	for _, entityID := range entityIDs {
+		ratelimitData, err := o.enterpriseClient.RemoveRoleAssignment(ctx, roleID, entityID, principal.Id.Resource)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return revokeResult(outputAnnotations, err, "failed to remove enterprise role")
+		}
+	}
+	return outputAnnotations, nil
+}
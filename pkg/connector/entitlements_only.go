@@ -0,0 +1,27 @@
+package connector
+
+import (
+	"context"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types"
+)
+
+// entitlementsOnlyServer answers grant listings of the given resource types
+// with an empty page instead of calling their builder. The resources and their
+// entitlements are still synced, which makes for a fast discovery sync of a
+// large organization before membership sync is enabled.
+type entitlementsOnlyServer struct {
+	types.ConnectorServer
+	resourceTypeIDs map[string]bool
+}
+
+func (e *entitlementsOnlyServer) ListGrants(
+	ctx context.Context,
+	request *v2.GrantsServiceListGrantsRequest,
+) (*v2.GrantsServiceListGrantsResponse, error) {
+	if e.resourceTypeIDs[request.GetResource().GetId().GetResourceType()] {
+		return &v2.GrantsServiceListGrantsResponse{}, nil
+	}
+	return e.ConnectorServer.ListGrants(ctx, request)
+}
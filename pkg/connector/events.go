@@ -0,0 +1,390 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// maxBufferedEvents bounds the events kept between two ListEvents calls.
+	// The oldest events are dropped first; the next full sync catches up on
+	// them.
+	maxBufferedEvents = 10000
+	// eventsPageSize is the number of events returned by ListEvents when the
+	// SDK doesn't ask for a size.
+	eventsPageSize = 100
+	// maxEventBodySize bounds the size of an Events API request.
+	maxEventBodySize = 1 << 20
+)
+
+// Events API event types that change a user.
+const (
+	eventUserChange        = "user_change"
+	eventTeamJoin          = "team_join"
+	eventUserStatusChanged = "user_status_changed"
+)
+
+// eventEnvelope is the body of an Events API request.
+type eventEnvelope struct {
+	Type      string          `json:"type"`
+	Challenge string          `json:"challenge"`
+	TeamID    string          `json:"team_id"`
+	EventID   string          `json:"event_id"`
+	EventTime int64           `json:"event_time"`
+	Event     json.RawMessage `json:"event"`
+}
+
+// userEvent is the inner event of the user event types, which all carry the
+// full user.
+type userEvent struct {
+	Type string     `json:"type"`
+	User slack.User `json:"user"`
+}
+
+// bufferedEvent is a received event waiting to be listed.
+type bufferedEvent struct {
+	seq        uint64
+	id         string
+	eventType  string
+	teamID     string
+	occurredAt time.Time
+	user       slack.User
+}
+
+// eventReceiver receives user events pushed by the Slack Events API and keeps
+// them until ListEvents hands them to the SDK, so that user changes are picked
+// up between full syncs.
+type eventReceiver struct {
+	addr          string
+	signingSecret string
+
+	mu     sync.Mutex
+	events []bufferedEvent
+	// seq is the sequence number of the last received event. Sequence numbers
+	// are the cursors of ListEvents. They start from the time the receiver was
+	// created, so that a cursor from before a restart doesn't skip new events.
+	seq uint64
+	// seen holds the IDs of the buffered events, since Slack delivers an event
+	// again when it isn't acknowledged in time.
+	seen map[string]bool
+}
+
+func newEventReceiver(addr string, signingSecret string) *eventReceiver {
+	return &eventReceiver{
+		addr:          addr,
+		signingSecret: signingSecret,
+		seq:           uint64(time.Now().UnixNano()),
+		seen:          make(map[string]bool),
+	}
+}
+
+func (e *eventReceiver) add(event bufferedEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.seen[event.id] {
+		return
+	}
+	e.seq++
+	event.seq = e.seq
+	e.events = append(e.events, event)
+	e.seen[event.id] = true
+
+	if len(e.events) > maxBufferedEvents {
+		dropped := len(e.events) - maxBufferedEvents
+		for _, old := range e.events[:dropped] {
+			delete(e.seen, old.id)
+		}
+		e.events = append([]bufferedEvent(nil), e.events[dropped:]...)
+	}
+}
+
+// after returns up to size events received after the given sequence number
+// and no earlier than since, and whether there are more.
+func (e *eventReceiver) after(seq uint64, since time.Time, size int) ([]bufferedEvent, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var rv []bufferedEvent
+	for _, event := range e.events {
+		if event.seq <= seq || event.occurredAt.Before(since) {
+			continue
+		}
+		if len(rv) == size {
+			return rv, true
+		}
+		rv = append(rv, event)
+	}
+	return rv, false
+}
+
+// last returns the sequence number of the last received event.
+func (e *eventReceiver) last() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.seq
+}
+
+// ServeHTTP handles Events API requests: it verifies their signature, answers
+// the URL verification challenge and buffers the user events.
+func (e *eventReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := ctxzap.Extract(r.Context())
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEventBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	verifier, err := slack.NewSecretsVerifier(r.Header, e.signingSecret)
+	if err == nil {
+		_, err = verifier.Write(body)
+	}
+	if err == nil {
+		err = verifier.Ensure()
+	}
+	if err != nil {
+		logger.Warn("baton-slack: rejected event with an invalid signature", zap.Error(err))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var envelope eventEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch envelope.Type {
+	case "url_verification":
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(envelope.Challenge))
+		return
+	case "event_callback":
+	default:
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var event userEvent
+	if err := json.Unmarshal(envelope.Event, &event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch event.Type {
+	case eventUserChange, eventTeamJoin, eventUserStatusChanged:
+		teamID := event.User.TeamID
+		if teamID == "" {
+			teamID = envelope.TeamID
+		}
+		e.add(bufferedEvent{
+			id:         envelope.EventID,
+			eventType:  event.Type,
+			teamID:     teamID,
+			occurredAt: time.Unix(envelope.EventTime, 0),
+			user:       event.User,
+		})
+	default:
+		logger.Debug("baton-slack: ignoring event", zap.String("event_type", event.Type))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ServeEvents receives the Slack Events API requests on /slack/events until
+// the context is done. It is a no-op unless WithEvents was given.
+func (s *Slack) ServeEvents(ctx context.Context) error {
+	if s.events == nil {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/slack/events", s.events)
+
+	server := &http.Server{
+		Addr:              s.events.addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	ctxzap.Extract(ctx).Info(
+		"baton-slack: receiving Slack events",
+		zap.String("addr", s.events.addr),
+	)
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// eventCursor is the cursor of ListEvents, which lists events from two
+// sources.
+type eventCursor struct {
+	// Received is the sequence number of the last Events API event listed.
+	Received uint64 `json:"received,omitempty"`
+	// Audit is the position in the audit logs.
+	Audit auditLogCursor `json:"audit,omitempty"`
+}
+
+func parseEventCursor(cursor string) (eventCursor, error) {
+	var rv eventCursor
+	if cursor == "" {
+		return rv, nil
+	}
+	if err := json.Unmarshal([]byte(cursor), &rv); err != nil {
+		return rv, fmt.Errorf("baton-slack: invalid event cursor %q: %w", cursor, err)
+	}
+	return rv, nil
+}
+
+// ListEvents returns the user events received from the Events API since the
+// cursor, each as a usage event of the changed user with the Slack event type
+// in its annotations, followed by a page of audit log entries when enabled.
+// Without a cursor, the feed starts at earliestEvent. Nothing is returned
+// unless WithEvents or WithAuditLogs was given.
+func (s *Slack) ListEvents(
+	ctx context.Context,
+	earliestEvent *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) (
+	[]*v2.Event,
+	*pagination.StreamState,
+	annotations.Annotations,
+	error,
+) {
+	if s.events == nil && !s.auditLogs {
+		return nil, &pagination.StreamState{Cursor: pToken.Cursor}, nil, nil
+	}
+
+	cursor, err := parseEventCursor(pToken.Cursor)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var since time.Time
+	if earliestEvent != nil {
+		since = earliestEvent.AsTime()
+	}
+
+	size := pToken.Size
+	if size <= 0 {
+		size = eventsPageSize
+	}
+
+	var rv []*v2.Event
+	var hasMore bool
+	if s.events != nil {
+		rv, cursor.Received, hasMore, err = s.receivedEvents(ctx, cursor.Received, since, size)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	outputAnnotations := annotations.New()
+	if s.auditLogs {
+		auditEvents, auditHasMore, ratelimitData, err := s.auditLogEvents(ctx, &cursor.Audit, since)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return nil, nil, outputAnnotations, err
+		}
+		rv = append(rv, auditEvents...)
+		hasMore = hasMore || auditHasMore
+	}
+
+	nextCursor, err := json.Marshal(cursor)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return rv, &pagination.StreamState{
+		Cursor:  string(nextCursor),
+		HasMore: hasMore,
+	}, outputAnnotations, nil
+}
+
+// receivedEvents returns up to size events received after seq, the sequence
+// number to continue from, and whether there are more.
+func (s *Slack) receivedEvents(
+	ctx context.Context,
+	seq uint64,
+	since time.Time,
+	size int,
+) ([]*v2.Event, uint64, bool, error) {
+	buffered, hasMore := s.events.after(seq, since, size)
+	next := s.events.last()
+	if len(buffered) > 0 && hasMore {
+		next = buffered[len(buffered)-1].seq
+	}
+
+	options := s.userOptions
+	options.enterpriseID = s.enterpriseID
+	options.externalUsers = make(map[string]bool)
+
+	rv := make([]*v2.Event, 0, len(buffered))
+	for _, event := range buffered {
+		user := event.user
+		if isExternalUser(&user, event.teamID, s.enterpriseID, s.cache) {
+			if !options.includeExternalUsers {
+				continue
+			}
+			options.externalUsers[user.ID] = true
+		}
+
+		workspaceID, err := resource.NewResourceID(resourceTypeWorkspace, event.teamID)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		r, err := userResource(ctx, &user, workspaceID, &options)
+		if err != nil {
+			return nil, 0, false, err
+		}
+
+		annos := annotations.New()
+		annos.Append(&structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"slack_event_type": structpb.NewStringValue(event.eventType),
+				"team_id":          structpb.NewStringValue(event.teamID),
+			},
+		})
+
+		rv = append(rv, &v2.Event{
+			Id:         event.id,
+			OccurredAt: timestamppb.New(event.occurredAt),
+			Event: &v2.Event_UsageEvent{
+				UsageEvent: &v2.UsageEvent{
+					TargetResource: r,
+					ActorResource:  r,
+				},
+			},
+			Annotations: annos,
+		})
+	}
+	return rv, next, hasMore, nil
+}
@@ -0,0 +1,250 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	resources "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-slack/pkg"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// conversationsPageSize is the largest page conversations.list returns.
+const conversationsPageSize = 1000
+
+// externalOrganization is an organization a workspace is connected to through
+// Slack Connect, along with the channels shared with it.
+type externalOrganization struct {
+	ID       string
+	Name     string
+	Channels []slack.Channel
+}
+
+type externalOrganizationResourceType struct {
+	resourceType *v2.ResourceType
+	client       *slack.Client
+	cache        *syncCache
+	// channels selects the shared channels organizations are derived from.
+	channels channelFilter
+	// names caches team.info lookups, an organization is usually connected to
+	// several workspaces.
+	names *lruCache[string, string]
+}
+
+func (o *externalOrganizationResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func externalOrganizationBuilder(
+	client *slack.Client,
+	channels channelFilter,
+	cache *syncCache,
+) *externalOrganizationResourceType {
+	return &externalOrganizationResourceType{
+		resourceType: resourceTypeExternalOrganization,
+		client:       client,
+		cache:        cache,
+		channels:     channels,
+		names:        newLRUCache[string, string](cache.size),
+	}
+}
+
+func externalOrganizationResource(
+	_ context.Context,
+	organization *externalOrganization,
+	parentResourceID *v2.ResourceId,
+) (*v2.Resource, error) {
+	channelIDs := make([]string, 0, len(organization.Channels))
+	channelNames := make([]string, 0, len(organization.Channels))
+	for _, channel := range organization.Channels {
+		channelIDs = append(channelIDs, channel.ID)
+		channelNames = append(channelNames, channel.Name)
+	}
+
+	return resources.NewGroupResource(
+		organization.Name,
+		resourceTypeExternalOrganization,
+		fmt.Sprintf("%s:%s", parentResourceID.Resource, organization.ID),
+		[]resources.GroupTraitOption{
+			resources.WithGroupProfile(
+				map[string]interface{}{
+					"team_id":              organization.ID,
+					"team_name":            organization.Name,
+					"shared_channel_ids":   strings.Join(channelIDs, ","),
+					"shared_channel_names": strings.Join(channelNames, ","),
+					"shared_channel_count": len(organization.Channels),
+				},
+			),
+		},
+		resources.WithParentResourceID(parentResourceID),
+	)
+}
+
+// List returns the organizations the workspace shares channels with. The
+// organizations are derived from the shared channels, so every channel has to
+// be read before any organization can be emitted.
+func (o *externalOrganizationResourceType) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	_ *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	organizations, err := o.externalOrganizations(ctx, parentResourceID.Resource)
+	if err != nil {
+		annos, err := pkg.AnnotationsForError(err)
+		return nil, "", annos, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(organizations))
+	for _, organization := range organizations {
+		r, err := externalOrganizationResource(ctx, organization, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, r)
+	}
+	return rv, "", nil, nil
+}
+
+func (o *externalOrganizationResourceType) externalOrganizations(
+	ctx context.Context,
+	teamID string,
+) ([]*externalOrganization, error) {
+	found := make(map[string]*externalOrganization)
+
+	err := pkg.ForEachPage("channels", func(cursor string) (string, error) {
+		channels, nextCursor, err := o.client.GetConversationsContext(
+			ctx,
+			&slack.GetConversationsParameters{
+				Cursor:          cursor,
+				ExcludeArchived: true,
+				Limit:           conversationsPageSize,
+				Types:           []string{"public_channel", "private_channel"},
+				TeamID:          teamID,
+			},
+		)
+		if err != nil {
+			return "", fmt.Errorf("baton-slack: error listing channels: %w", err)
+		}
+
+		for _, channel := range channels {
+			if !channel.IsExtShared {
+				continue
+			}
+			if !o.channels.matches(channel.Name) {
+				ctxzap.Extract(ctx).Debug(
+					"baton-slack: skipping shared channel filtered out by name",
+					zap.String("channel_id", channel.ID),
+					zap.String("channel_name", channel.Name),
+				)
+				continue
+			}
+			for _, id := range connectedTeamIDs(channel) {
+				// Channels shared within an organization list its other
+				// workspaces too.
+				if o.cache.isWorkspace(id) || id == teamID {
+					continue
+				}
+				organization, ok := found[id]
+				if !ok {
+					organization = &externalOrganization{ID: id}
+					found[id] = organization
+				}
+				organization.Channels = append(organization.Channels, channel)
+			}
+		}
+
+		return nextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]*externalOrganization, 0, len(found))
+	for _, organization := range found {
+		organization.Name = o.name(ctx, organization.ID)
+		rv = append(rv, organization)
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].ID < rv[j].ID
+	})
+	return rv, nil
+}
+
+// name returns the name of an external organization, falling back to its ID
+// when team.info doesn't disclose it.
+func (o *externalOrganizationResourceType) name(ctx context.Context, teamID string) string {
+	if name, ok := o.names.get(teamID); ok {
+		return name
+	}
+
+	name := teamID
+	teamInfo, err := o.client.GetOtherTeamInfoContext(ctx, teamID)
+	if err != nil {
+		ctxzap.Extract(ctx).Debug(
+			"baton-slack: error fetching external organization",
+			zap.String("team_id", teamID),
+			zap.Error(err),
+		)
+	} else if teamInfo.Name != "" {
+		name = teamInfo.Name
+	}
+
+	o.names.add(teamID, name)
+	return name
+}
+
+// connectedTeamIDs returns every team a shared channel is connected to.
+// Depending on the token, Slack reports them in either field.
+func connectedTeamIDs(channel slack.Channel) []string {
+	seen := make(map[string]bool)
+	var rv []string
+	for _, id := range append(channel.ConnectedTeamIDs, channel.SharedTeamIDs...) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			rv = append(rv, id)
+		}
+	}
+	return rv
+}
+
+func (o *externalOrganizationResourceType) Entitlements(
+	_ context.Context,
+	_ *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return nil, "", nil, nil
+}
+
+func (o *externalOrganizationResourceType) Grants(
+	_ context.Context,
+	_ *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return nil, "", nil, nil
+}
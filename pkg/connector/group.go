@@ -3,7 +3,6 @@ package connector
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -25,18 +24,20 @@ type groupResourceType struct {
 	enterpriseID     string
 	enterpriseClient *enterprise.Client
 	ssoEnabled       bool
+	cache            *syncCache
 }
 
 func (g *groupResourceType) ResourceType(_ context.Context) *v2.ResourceType {
 	return g.resourceType
 }
 
-func groupBuilder(enterpriseClient *enterprise.Client, enterpriseID string, ssoEnabled bool) *groupResourceType {
+func groupBuilder(enterpriseClient *enterprise.Client, enterpriseID string, ssoEnabled bool, cache *syncCache) *groupResourceType {
 	return &groupResourceType{
 		resourceType:     resourceTypeGroup,
 		enterpriseID:     enterpriseID,
 		enterpriseClient: enterpriseClient,
 		ssoEnabled:       ssoEnabled,
+		cache:            cache,
 	}
 }
 
@@ -61,38 +62,6 @@ func groupResource(
 	)
 }
 
-// parsePaginationToken - takes as pagination token and returns offset and limit
-// in that order. TODO(marcos): move this to a util.
-func parsePaginationToken(pToken *pagination.Token) (int, int, error) {
-	var (
-		limit  = enterprise.PageSizeDefault
-		offset = StartingOffset
-	)
-
-	if pToken != nil {
-		if pToken.Size > 0 {
-			limit = pToken.Size
-		}
-
-		if pToken.Token != "" {
-			parsedOffset, err := strconv.Atoi(pToken.Token)
-			if err != nil {
-				return 0, 0, err
-			}
-			offset = parsedOffset
-		}
-	}
-	return offset, limit, nil
-}
-
-func getNextToken(offset int, limit int, total int) string {
-	nextOffset := offset + limit
-	if nextOffset >= total {
-		return ""
-	}
-	return strconv.Itoa(nextOffset)
-}
-
 func (g *groupResourceType) List(
 	ctx context.Context,
 	parentResourceId *v2.ResourceId,
@@ -107,18 +76,40 @@ func (g *groupResourceType) List(
 		return nil, "", nil, nil
 	}
 
-	offset, limit, err := parsePaginationToken(pageToken)
+	if err := ctx.Err(); err != nil {
+		return nil, "", nil, err
+	}
+
+	offset, limit, err := pkg.ParseOffsetToken(pageToken, StartingOffset, enterprise.PageSizeDefault)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	page := (offset-StartingOffset)/limit + 1
+	if page > enterprise.SCIMMaxPages {
+		return nil, "", nil, fmt.Errorf(
+			"baton-slack: listing IDP groups exceeded %d pages, the SCIM API keeps reporting more results",
+			enterprise.SCIMMaxPages,
+		)
+	}
+
 	outputAnnotations := annotations.New()
-	groupsResponse, ratelimitData, err := g.enterpriseClient.ListIDPGroups(ctx, limit, offset)
+	groupsResponse, ratelimitData, err := g.enterpriseClient.ListIDPGroups(ctx, offset, limit)
 	outputAnnotations.WithRateLimiting(ratelimitData)
 	if err != nil {
 		return nil, "", outputAnnotations, err
 	}
 
+	g.cache.setIDPGroups(groupsResponse.Resources)
+
+	ctxzap.Extract(ctx).Debug(
+		"baton-slack: listed IDP groups",
+		zap.Int("page", page),
+		zap.Int("start_index", offset),
+		zap.Int("count", len(groupsResponse.Resources)),
+		zap.Int("total_results", groupsResponse.TotalResults),
+	)
+
 	groups, err := pkg.MakeResourceList(
 		ctx,
 		groupsResponse.Resources,
@@ -129,7 +120,11 @@ func (g *groupResourceType) List(
 		return nil, "", nil, err
 	}
 
-	nextToken := getNextToken(offset, limit, groupsResponse.TotalResults)
+	// An empty page means we are past the end, whatever totalResults says.
+	nextToken := ""
+	if len(groupsResponse.Resources) > 0 {
+		nextToken = pkg.NextOffsetToken(offset, limit, groupsResponse.TotalResults)
+	}
 
 	return groups, nextToken, outputAnnotations, nil
 }
@@ -182,7 +177,7 @@ func (g *groupResourceType) Grants(
 	outputAnnotations := annotations.New()
 
 	var rv []*v2.Grant
-	group, ratelimitData, err := g.enterpriseClient.GetIDPGroup(ctx, resource.Id.Resource)
+	group, ratelimitData, err := g.cache.getIDPGroup(ctx, g.enterpriseClient, resource.Id.Resource)
 	outputAnnotations.WithRateLimiting(ratelimitData)
 	if err != nil {
 		return nil, "", outputAnnotations, err
@@ -194,7 +189,7 @@ func (g *groupResourceType) Grants(
 			return nil, "", nil, err
 		}
 
-		grant := grant.NewGrant(resource, memberEntitlement, userID)
+		grant := grant.NewGrant(resource, memberEntitlement, userID, withGrantSource(grantSourceSCIMGroupMembers))
 		rv = append(rv, grant)
 	}
 
@@ -221,14 +216,18 @@ func (g *groupResourceType) Grant(
 	}
 
 	outputAnnotations := annotations.New()
-	ratelimitData, err := g.enterpriseClient.AddUserToGroup(
+	wasAdded, ratelimitData, err := g.enterpriseClient.AddUserToGroup(
 		ctx,
 		entitlement.Resource.Id.Resource,
 		principal.Id.Resource,
 	)
 	outputAnnotations.WithRateLimiting(ratelimitData)
 	if err != nil {
-		return outputAnnotations, fmt.Errorf("baton-slack: failed to add user to an IDP group: %w", err)
+		return grantResult(outputAnnotations, err, "failed to add user to an IDP group")
+	}
+
+	if !wasAdded {
+		outputAnnotations.Append(&v2.GrantAlreadyExists{})
 	}
 
 	return outputAnnotations, nil
@@ -262,9 +261,8 @@ func (g *groupResourceType) Revoke(
 		principal.Id.Resource,
 	)
 	outputAnnotations.WithRateLimiting(ratelimitData)
-
 	if err != nil {
-		return outputAnnotations, fmt.Errorf("baton-slack: failed to remove user from IDP group: %w", err)
+		return revokeResult(outputAnnotations, err, "failed to remove user from IDP group")
 	}
 
 	if !wasRevoked {
@@ -0,0 +1,47 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// guestChannelMetadata returns the grant metadata naming the channel a
+// single-channel guest is restricted to, so that reviewers can tell what the
+// guest has access to. It returns nil if the channel can't be resolved, which
+// never fails the sync.
+func (o *workspaceResourceType) guestChannelMetadata(
+	ctx context.Context,
+	teamID string,
+	userID string,
+) map[string]interface{} {
+	params := &slack.GetConversationsForUserParameters{
+		UserID:          userID,
+		Types:           []string{"public_channel", "private_channel"},
+		ExcludeArchived: true,
+	}
+	if o.enterpriseID != "" {
+		params.TeamID = teamID
+	}
+
+	channels, _, err := o.client.GetConversationsForUserContext(ctx, params)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn(
+			"baton-slack: error resolving the channel of a single-channel guest",
+			zap.String("user_id", userID),
+			zap.String("team_id", teamID),
+			zap.Error(err),
+		)
+		return nil
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"channel_id":   channels[0].ID,
+		"channel_name": channels[0].Name,
+	}
+}
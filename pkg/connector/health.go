@@ -0,0 +1,323 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	"github.com/conductorone/baton-slack/pkg"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tokenCheckInterval bounds how often probes actually hit auth.test, so that
+// an aggressive probe configuration doesn't eat into the rate limit budget.
+const tokenCheckInterval = time.Minute
+
+// healthReport is the body served by the health endpoint.
+type healthReport struct {
+	Status               string     `json:"status"`
+	LastSuccessfulSyncAt *time.Time `json:"last_successful_sync_at,omitempty"`
+	LastSyncError        string     `json:"last_sync_error,omitempty"`
+	LastSyncErrorAt      *time.Time `json:"last_sync_error_at,omitempty"`
+	Token                struct {
+		Valid     bool      `json:"valid"`
+		CheckedAt time.Time `json:"checked_at"`
+		Error     string    `json:"error,omitempty"`
+	} `json:"token"`
+	EventListener struct {
+		Status        string     `json:"status"`
+		LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+		LastError     string     `json:"last_error,omitempty"`
+		LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	} `json:"event_listener"`
+}
+
+// healthState keeps what the health endpoint reports. Sync and event activity
+// is recorded by healthTrackingServer, the token is checked lazily.
+type healthState struct {
+	client *slack.Client
+	addr   string
+
+	mu       sync.Mutex
+	lastSync time.Time
+	// syncing is set while a sync runs and hasn't failed. A sync starts when
+	// the SDK lists the resource types and completes once the grants of every
+	// resource it listed are listed; the SDK doesn't tell the connector when
+	// it ends. A sync resumed after a restart is never reported as successful.
+	syncing bool
+	// grantsPending holds the resources whose grants are yet to be listed.
+	grantsPending map[string]bool
+	// skipGrants holds the resource types whose grants the SDK doesn't list.
+	skipGrants        map[string]bool
+	lastSyncErr       error
+	lastSyncErrTime   time.Time
+	lastEvents        time.Time
+	lastEventsErr     error
+	lastEventsErrTime time.Time
+	tokenCheckedAt    time.Time
+	tokenErr          error
+}
+
+func healthResourceKey(id *v2.ResourceId) string {
+	return id.GetResourceType() + "/" + id.GetResource()
+}
+
+// startSync starts tracking a sync of the given resource types.
+func (h *healthState) startSync(resourceTypes []*v2.ResourceType, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.syncing = true
+	h.grantsPending = make(map[string]bool)
+	h.skipGrants = make(map[string]bool)
+	for _, resourceType := range resourceTypes {
+		annos := annotations.Annotations(resourceType.GetAnnotations())
+		if annos.Contains(&v2.SkipEntitlementsAndGrants{}) {
+			h.skipGrants[resourceType.GetId()] = true
+		}
+	}
+	h.failSync(err)
+}
+
+// recordResources records the resources whose grants the sync will list.
+func (h *healthState) recordResources(resources []*v2.Resource, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.syncing || h.failSync(err) {
+		return
+	}
+	for _, r := range resources {
+		if !h.skipGrants[r.GetId().GetResourceType()] {
+			h.grantsPending[healthResourceKey(r.GetId())] = true
+		}
+	}
+}
+
+func (h *healthState) recordEntitlements(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failSync(err)
+}
+
+// recordGrants records a page of grants of the given resource, completing the
+// sync once the last pending resource is done.
+func (h *healthState) recordGrants(resourceID *v2.ResourceId, nextPageToken string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.syncing || h.failSync(err) || nextPageToken != "" {
+		return
+	}
+	delete(h.grantsPending, healthResourceKey(resourceID))
+	if len(h.grantsPending) == 0 {
+		h.syncing = false
+		h.lastSync = time.Now()
+		h.lastSyncErr = nil
+	}
+}
+
+// failSync ends the current sync as failed and reports whether err did so.
+// Errors the SDK waits out and retries don't fail the sync. It must be called
+// with mu held.
+func (h *healthState) failSync(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return false
+	}
+	if h.syncing {
+		h.syncing = false
+		h.lastSyncErr = err
+		h.lastSyncErrTime = time.Now()
+	}
+	return true
+}
+
+func (h *healthState) recordEvents(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		h.lastEventsErr = err
+		h.lastEventsErrTime = time.Now()
+		return
+	}
+	h.lastEvents = time.Now()
+	h.lastEventsErr = nil
+}
+
+// checkToken returns the result of the last auth.test call, refreshing it
+// when it is older than tokenCheckInterval.
+func (h *healthState) checkToken(ctx context.Context) (time.Time, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.tokenCheckedAt.IsZero() && time.Since(h.tokenCheckedAt) < tokenCheckInterval {
+		return h.tokenCheckedAt, h.tokenErr
+	}
+
+	_, err := h.client.AuthTestContext(ctx)
+	h.tokenCheckedAt = time.Now()
+	h.tokenErr = pkg.WrapTokenError(err)
+	return h.tokenCheckedAt, h.tokenErr
+}
+
+func (h *healthState) report(ctx context.Context) healthReport {
+	var report healthReport
+
+	checkedAt, tokenErr := h.checkToken(ctx)
+	report.Token.CheckedAt = checkedAt
+	report.Token.Valid = tokenErr == nil
+	if tokenErr != nil {
+		report.Token.Error = tokenErr.Error()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.lastSync.IsZero() {
+		lastSync := h.lastSync
+		report.LastSuccessfulSyncAt = &lastSync
+	}
+	if h.lastSyncErr != nil {
+		report.LastSyncError = h.lastSyncErr.Error()
+		lastSyncErrAt := h.lastSyncErrTime
+		report.LastSyncErrorAt = &lastSyncErrAt
+	}
+
+	switch {
+	case h.lastEventsErr != nil:
+		report.EventListener.Status = "failing"
+		report.EventListener.LastError = h.lastEventsErr.Error()
+		lastErrorAt := h.lastEventsErrTime
+		report.EventListener.LastErrorAt = &lastErrorAt
+	case !h.lastEvents.IsZero():
+		report.EventListener.Status = "running"
+	default:
+		report.EventListener.Status = "idle"
+	}
+	if !h.lastEvents.IsZero() {
+		lastEvents := h.lastEvents
+		report.EventListener.LastSuccessAt = &lastEvents
+	}
+
+	report.Status = "ok"
+	if !report.Token.Valid {
+		report.Status = "unhealthy"
+	}
+	return report
+}
+
+// ServeHTTP reports 200 while the token is usable and 503 otherwise, so it
+// can back both liveness and readiness probes.
+func (h *healthState) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.report(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// ServeHealth serves the health endpoint until the context is done. It is a
+// no-op unless WithHealthAddr was given.
+func (s *Slack) ServeHealth(ctx context.Context) error {
+	if s.health == nil {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", s.health)
+
+	server := &http.Server{
+		Addr:              s.health.addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	ctxzap.Extract(ctx).Info(
+		"baton-slack: serving health endpoint",
+		zap.String("addr", s.health.addr),
+	)
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// healthTrackingServer follows the syncs and event feed calls made by the SDK
+// so that the health endpoint can report on them.
+type healthTrackingServer struct {
+	types.ConnectorServer
+	health *healthState
+}
+
+func (h *healthTrackingServer) ListResourceTypes(
+	ctx context.Context,
+	request *v2.ResourceTypesServiceListResourceTypesRequest,
+) (*v2.ResourceTypesServiceListResourceTypesResponse, error) {
+	response, err := h.ConnectorServer.ListResourceTypes(ctx, request)
+	h.health.startSync(response.GetList(), err)
+	return response, err
+}
+
+func (h *healthTrackingServer) ListResources(
+	ctx context.Context,
+	request *v2.ResourcesServiceListResourcesRequest,
+) (*v2.ResourcesServiceListResourcesResponse, error) {
+	response, err := h.ConnectorServer.ListResources(ctx, request)
+	h.health.recordResources(response.GetList(), err)
+	return response, err
+}
+
+func (h *healthTrackingServer) ListEntitlements(
+	ctx context.Context,
+	request *v2.EntitlementsServiceListEntitlementsRequest,
+) (*v2.EntitlementsServiceListEntitlementsResponse, error) {
+	response, err := h.ConnectorServer.ListEntitlements(ctx, request)
+	h.health.recordEntitlements(err)
+	return response, err
+}
+
+func (h *healthTrackingServer) ListGrants(
+	ctx context.Context,
+	request *v2.GrantsServiceListGrantsRequest,
+) (*v2.GrantsServiceListGrantsResponse, error) {
+	response, err := h.ConnectorServer.ListGrants(ctx, request)
+	h.health.recordGrants(request.GetResource().GetId(), response.GetNextPageToken(), err)
+	return response, err
+}
+
+func (h *healthTrackingServer) ListEvents(
+	ctx context.Context,
+	request *v2.ListEventsRequest,
+) (*v2.ListEventsResponse, error) {
+	response, err := h.ConnectorServer.ListEvents(ctx, request)
+	h.health.recordEvents(err)
+	return response, err
+}
@@ -0,0 +1,57 @@
+package connector
+
+import (
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+// Slack error codes reporting that a grant is already in place, or already
+// gone. Repeating a grant or a revoke is expected during reconciliation, so
+// these are reported with the GrantAlreadyExists and GrantAlreadyRevoked
+// annotations rather than as failures.
+var (
+	alreadyGrantedCodes = map[string]bool{
+		"already_in_channel":       true,
+		"user_already_team_member": true,
+	}
+	alreadyRevokedCodes = map[string]bool{
+		"not_in_channel":       true,
+		"user_already_deleted": true,
+	}
+)
+
+// grantResult returns the outcome of a grant: nil with GrantAlreadyExists if
+// Slack reports the access is already in place, or the error wrapped with the
+// given description of the failure.
+func grantResult(
+	outputAnnotations annotations.Annotations,
+	err error,
+	failure string,
+) (annotations.Annotations, error) {
+	if err == nil {
+		return outputAnnotations, nil
+	}
+	if alreadyGrantedCodes[slackErrorCode(err)] {
+		outputAnnotations.Append(&v2.GrantAlreadyExists{})
+		return outputAnnotations, nil
+	}
+	return outputAnnotations, fmt.Errorf("baton-slack: %s: %w", failure, err)
+}
+
+// revokeResult is grantResult for revokes, using GrantAlreadyRevoked.
+func revokeResult(
+	outputAnnotations annotations.Annotations,
+	err error,
+	failure string,
+) (annotations.Annotations, error) {
+	if err == nil {
+		return outputAnnotations, nil
+	}
+	if alreadyRevokedCodes[slackErrorCode(err)] {
+		outputAnnotations.Append(&v2.GrantAlreadyRevoked{})
+		return outputAnnotations, nil
+	}
+	return outputAnnotations, fmt.Errorf("baton-slack: %s: %w", failure, err)
+}
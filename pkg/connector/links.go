@@ -0,0 +1,34 @@
+package connector
+
+import (
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// Deep links into the Slack web client so that reviewers can jump straight
+// from a resource to Slack.
+const slackAppURL = "https://app.slack.com"
+
+func workspaceLink(teamID string) *v2.ExternalLink {
+	return &v2.ExternalLink{Url: fmt.Sprintf("%s/client/%s", slackAppURL, teamID)}
+}
+
+func channelLink(teamID string, channelID string) *v2.ExternalLink {
+	return &v2.ExternalLink{Url: fmt.Sprintf("%s/client/%s/%s", slackAppURL, teamID, channelID)}
+}
+
+// userLink points to the profile of the user. On Enterprise Grid the profile
+// URL depends on the workspace the reviewer is signed in to, so the user is
+// linked in the admin console of the organization instead.
+func userLink(enterpriseID string, userID string) *v2.ExternalLink {
+	if enterpriseID != "" {
+		return adminUserLink(enterpriseID, userID)
+	}
+	return &v2.ExternalLink{Url: fmt.Sprintf("%s/team/%s", slackAppURL, userID)}
+}
+
+// adminUserLink points to the user in the admin console of the organization.
+func adminUserLink(enterpriseID string, userID string) *v2.ExternalLink {
+	return &v2.ExternalLink{Url: fmt.Sprintf("%s/manage/%s/people/user/%s", slackAppURL, enterpriseID, userID)}
+}
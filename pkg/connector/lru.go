@@ -0,0 +1,64 @@
+package connector
+
+import (
+	"container/list"
+)
+
+// defaultCacheSize is the number of entries each in-memory cache keeps unless
+// configured otherwise.
+const defaultCacheSize = 10000
+
+// lruCache is a size-bounded cache evicting the least recently used entry, so
+// that memory usage stays predictable on very large organizations. It is not
+// safe for concurrent use, like the builders using it.
+type lruCache[K comparable, V any] struct {
+	size  int
+	order *list.List
+	items map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRUCache[K comparable, V any](size int) *lruCache[K, V] {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	return &lruCache[K, V]{
+		size:  size,
+		order: list.New(),
+		items: make(map[K]*list.Element),
+	}
+}
+
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	element, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+func (c *lruCache[K, V]) add(key K, value V) {
+	if element, ok := c.items[key]; ok {
+		element.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+func (c *lruCache[K, V]) purge() {
+	c.order.Init()
+	c.items = make(map[K]*list.Element)
+}
@@ -0,0 +1,137 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-slack/pkg"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// failureNotification is the JSON body posted to the failure webhook. `text`
+// makes the payload usable as-is with a Slack incoming webhook.
+type failureNotification struct {
+	Text          string `json:"text"`
+	Operation     string `json:"operation"`
+	Error         string `json:"error"`
+	ErrorCode     string `json:"error_code,omitempty"`
+	PrincipalType string `json:"principal_type,omitempty"`
+	PrincipalID   string `json:"principal_id,omitempty"`
+	TargetID      string `json:"target_id,omitempty"`
+}
+
+// failureNotifier posts to a webhook when a provisioning operation fails. It
+// is only called with the error the server finally returns, i.e. after the
+// HTTP client has exhausted its retries.
+type failureNotifier struct {
+	httpClient *http.Client
+	webhookURL string
+}
+
+// notify posts the failure. Failing to post is logged but never changes the
+// outcome of the operation itself.
+func (n *failureNotifier) notify(
+	ctx context.Context,
+	operation string,
+	principal *v2.ResourceId,
+	target string,
+	opErr error,
+) {
+	if n == nil || n.webhookURL == "" || opErr == nil {
+		return
+	}
+
+	notification := failureNotification{
+		Operation: operation,
+		Error:     opErr.Error(),
+		ErrorCode: slackErrorCode(opErr),
+		TargetID:  target,
+	}
+	if principal != nil {
+		notification.PrincipalType = principal.ResourceType
+		notification.PrincipalID = principal.Resource
+	}
+	notification.Text = notification.summary()
+
+	l := ctxzap.Extract(ctx).With(
+		zap.String("operation", operation),
+	)
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		l.Warn("baton-slack: failed to encode failure notification", zap.Error(err))
+		return
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		l.Warn("baton-slack: failed to create failure notification request", zap.Error(err))
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := n.httpClient.Do(request)
+	if err != nil {
+		l.Warn("baton-slack: failed to send failure notification", zap.Error(err))
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		l.Warn(
+			"baton-slack: failure notification rejected by webhook",
+			zap.Int("status_code", response.StatusCode),
+		)
+	}
+}
+
+func (f failureNotification) summary() string {
+	lines := []string{
+		fmt.Sprintf("*baton-slack %s failed*: %s", f.Operation, f.Error),
+	}
+	if f.ErrorCode != "" {
+		lines = append(lines, fmt.Sprintf("• Slack error: `%s`", f.ErrorCode))
+	}
+	if f.PrincipalID != "" {
+		lines = append(lines, fmt.Sprintf("• Principal: `%s:%s`", f.PrincipalType, f.PrincipalID))
+	}
+	if f.TargetID != "" {
+		lines = append(lines, fmt.Sprintf("• Target: `%s`", f.TargetID))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// slackErrorCode digs the error code reported by Slack out of the error chain,
+// if there is one.
+func slackErrorCode(err error) string {
+	var apiErr *enterprise.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+
+	var tokenErr *pkg.TokenHealthError
+	if errors.As(err, &tokenErr) {
+		return tokenErr.Code
+	}
+
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) {
+		return slackErr.Err
+	}
+
+	var scimErr *enterprise.SCIMError
+	if errors.As(err, &scimErr) {
+		return fmt.Sprintf("scim_%s", scimErr.Status)
+	}
+
+	return ""
+}
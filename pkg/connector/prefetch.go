@@ -0,0 +1,158 @@
+package connector
+
+import (
+	"context"
+	"sync"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// pagePrefetcher fetches the first page of users of the listed workspaces
+// ahead of their Grants calls, so that an organization with hundreds of
+// workspaces isn't listed one request at a time. At most concurrency pages
+// are being fetched or waiting to be taken at once, and every request still
+// goes through the rate limiter, which keeps them within their tier.
+type pagePrefetcher[T any] struct {
+	concurrency int
+	fetch       func(ctx context.Context, teamID string) ([]T, string, *v2.RateLimitDescription, error)
+
+	mu      sync.Mutex
+	teamIDs []string
+	started bool
+	cancel  context.CancelFunc
+	slots   chan struct{}
+	pages   map[string]*prefetchedPage[T]
+	// claimed holds the workspaces whose first page was asked for, so that
+	// it isn't fetched twice.
+	claimed map[string]bool
+}
+
+type prefetchedPage[T any] struct {
+	done          chan struct{}
+	items         []T
+	nextCursor    string
+	ratelimitData *v2.RateLimitDescription
+	err           error
+}
+
+// newPagePrefetcher returns a prefetcher, or nil when concurrency doesn't
+// allow any request ahead of the current one.
+func newPagePrefetcher[T any](
+	concurrency int,
+	fetch func(ctx context.Context, teamID string) ([]T, string, *v2.RateLimitDescription, error),
+) *pagePrefetcher[T] {
+	if concurrency <= 1 {
+		return nil
+	}
+	p := &pagePrefetcher[T]{
+		concurrency: concurrency,
+		fetch:       fetch,
+	}
+	p.reset()
+	return p
+}
+
+// reset stops the prefetching of the previous sync and forgets its workspaces.
+func (p *pagePrefetcher[T]) reset() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.teamIDs = nil
+	p.started = false
+	p.cancel = nil
+	p.slots = make(chan struct{}, p.concurrency)
+	p.pages = make(map[string]*prefetchedPage[T])
+	p.claimed = make(map[string]bool)
+}
+
+// add queues listed workspaces, in the order their grants are expected.
+func (p *pagePrefetcher[T]) add(teamIDs ...string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.teamIDs = append(p.teamIDs, teamIDs...)
+}
+
+// start begins prefetching the queued workspaces. The requests outlive the
+// call that starts them, until the next reset.
+func (p *pagePrefetcher[T]) start(ctx context.Context) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		return
+	}
+	p.started = true
+
+	ctx, p.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	teamIDs := p.teamIDs
+	slots := p.slots
+	go func() {
+		for _, teamID := range teamIDs {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			p.mu.Lock()
+			if p.claimed[teamID] || ctx.Err() != nil {
+				p.mu.Unlock()
+				<-slots
+				continue
+			}
+			page := &prefetchedPage[T]{done: make(chan struct{})}
+			p.pages[teamID] = page
+			p.mu.Unlock()
+
+			go func(teamID string) {
+				defer close(page.done)
+				page.items, page.nextCursor, page.ratelimitData, page.err = p.fetch(ctx, teamID)
+			}(teamID)
+		}
+	}()
+}
+
+// take returns the prefetched first page of a workspace, waiting for it if it
+// is still being fetched. It reports false if the page wasn't prefetched or
+// failed, in which case the caller fetches it itself.
+func (p *pagePrefetcher[T]) take(ctx context.Context, teamID string) (*prefetchedPage[T], bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mu.Lock()
+	p.claimed[teamID] = true
+	page, ok := p.pages[teamID]
+	delete(p.pages, teamID)
+	slots := p.slots
+	p.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	select {
+	case <-page.done:
+		<-slots
+	case <-ctx.Done():
+		go func() {
+			<-page.done
+			<-slots
+		}()
+		return nil, false
+	}
+
+	if page.err != nil {
+		return nil, false
+	}
+	return page, true
+}
@@ -0,0 +1,91 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types"
+)
+
+type provisioningPriorityKey struct{}
+
+// withProvisioningPriority marks the requests made with the returned context
+// as part of a provisioning operation, i.e. a grant, revoke, account creation
+// or action. Syncs and provisioning share the rate limits of the same tokens,
+// so the rate limiter lets these requests go ahead of sync listings.
+func withProvisioningPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, provisioningPriorityKey{}, true)
+}
+
+func hasProvisioningPriority(ctx context.Context) bool {
+	return ctx.Value(provisioningPriorityKey{}) != nil
+}
+
+// sharedTransport is the HTTP transport shared by every Slack client of the
+// connector. It applies the rate limiter to each outgoing request.
+type sharedTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+}
+
+func (t *sharedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if method := apiMethod(req.URL.Path); method != "" {
+		priority := hasProvisioningPriority(req.Context())
+		if err := t.limiter.wait(req.Context(), rateLimitBucket(req, method), method, priority); err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// withSharedTransport routes the requests of the given client through the rate
+// limiter.
+func withSharedTransport(client *http.Client, limiter *rateLimiter) {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &sharedTransport{
+		base:    base,
+		limiter: limiter,
+	}
+}
+
+// priorityServer runs the provisioning operations of the SDK with priority
+// over sync listings.
+type priorityServer struct {
+	types.ConnectorServer
+}
+
+func (p *priorityServer) Grant(
+	ctx context.Context,
+	request *v2.GrantManagerServiceGrantRequest,
+) (*v2.GrantManagerServiceGrantResponse, error) {
+	ctx = withProvisioningPriority(ctx)
+	return p.ConnectorServer.Grant(ctx, request)
+}
+
+func (p *priorityServer) Revoke(
+	ctx context.Context,
+	request *v2.GrantManagerServiceRevokeRequest,
+) (*v2.GrantManagerServiceRevokeResponse, error) {
+	ctx = withProvisioningPriority(ctx)
+	return p.ConnectorServer.Revoke(ctx, request)
+}
+
+func (p *priorityServer) CreateAccount(
+	ctx context.Context,
+	request *v2.CreateAccountRequest,
+) (*v2.CreateAccountResponse, error) {
+	ctx = withProvisioningPriority(ctx)
+	return p.ConnectorServer.CreateAccount(ctx, request)
+}
+
+func (p *priorityServer) DeleteResource(
+	ctx context.Context,
+	request *v2.DeleteResourceRequest,
+) (*v2.DeleteResourceResponse, error) {
+	ctx = withProvisioningPriority(ctx)
+	return p.ConnectorServer.DeleteResource(ctx, request)
+}
@@ -0,0 +1,40 @@
+package connector
+
+import (
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+)
+
+// Grant sources name the Slack API, and the part of its response, a grant was
+// derived from. They are attached to every grant so that a disputed grant can
+// be traced back without reproducing the sync.
+const (
+	grantSourceUsersList                = "users.list flags"
+	grantSourceUsersListEnterprise      = "users.list enterprise_user flags"
+	grantSourceAdminUsersList           = "admin.users.list flags"
+	grantSourceRoleAssignments          = "admin.roles.listAssignments"
+	grantSourceAuthPolicyEntities       = "admin.auth.policy.getEntities"
+	grantSourceSCIMGroupMembers         = "SCIM group members"
+	grantSourceUserGroupMembers         = "usergroups.users.list"
+	grantSourceOrgUserGroupMembers      = "admin.usergroups.listUsers"
+	grantSourceOrgUserGroupWorkspace    = "usergroups.list enterprise_subteam"
+	grantSourceConversationMembers      = "conversations.members"
+	grantSourceUserGroupDefaultChannels = "usergroups.list prefs.channels"
+	grantSourceAppsApproved             = "admin.apps.approved.list"
+)
+
+// withGrantSource records the source of a grant in its metadata.
+func withGrantSource(source string) grant.GrantOption {
+	return withGrantSourceMetadata(source, nil)
+}
+
+// withGrantSourceMetadata records the source of a grant in its metadata along
+// with the given fields. Grants carry a single metadata annotation, so every
+// field has to be set at once.
+func withGrantSourceMetadata(source string, metadata map[string]interface{}) grant.GrantOption {
+	fields := make(map[string]interface{}, len(metadata)+1)
+	for key, value := range metadata {
+		fields[key] = value
+	}
+	fields["source"] = source
+	return grant.WithGrantMetadata(fields)
+}
@@ -0,0 +1,137 @@
+package connector
+
+import (
+	"context"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types"
+)
+
+// provisioningServer wraps the server built by the SDK so that every
+// provisioning operation goes through a single place, regardless of which
+// resource type handles it.
+type provisioningServer struct {
+	types.ConnectorServer
+	audit    *auditLog
+	notifier *failureNotifier
+}
+
+// WrapServer adds the connector-wide grant filtering, sync statistics,
+// provisioning, scheduling, retry, read-only and health hooks to the server built by the SDK.
+func (s *Slack) WrapServer(server types.ConnectorServer) types.ConnectorServer {
+	if len(s.entitlementsOnlyResourceTypes) > 0 {
+		server = &entitlementsOnlyServer{
+			ConnectorServer: server,
+			resourceTypeIDs: s.entitlementsOnlyResourceTypes,
+		}
+	}
+
+	server = &statsTrackingServer{
+		ConnectorServer: server,
+		stats:           newSyncStats(s.syncStatsAnnotations),
+	}
+
+	if s.health != nil {
+		server = &healthTrackingServer{
+			ConnectorServer: server,
+			health:          s.health,
+		}
+	}
+
+	if s.audit != nil || s.notifier != nil {
+		server = &provisioningServer{
+			ConnectorServer: server,
+			audit:           s.audit,
+			notifier:        s.notifier,
+		}
+	}
+
+	server = &priorityServer{
+		ConnectorServer: server,
+	}
+
+	if s.retries != nil {
+		// Retries run beneath the queue so that they don't queue themselves.
+		s.retries.server = server
+		server = &retryServer{
+			ConnectorServer: server,
+			queue:           s.retries,
+		}
+	}
+
+	// Checked last so that rejected operations are neither audited nor
+	// reported as failures.
+	if s.readOnly {
+		server = &readOnlyServer{ConnectorServer: server}
+	}
+	return server
+}
+
+func (p *provisioningServer) Grant(
+	ctx context.Context,
+	request *v2.GrantManagerServiceGrantRequest,
+) (*v2.GrantManagerServiceGrantResponse, error) {
+	response, err := p.ConnectorServer.Grant(ctx, request)
+	p.audit.record(
+		ctx,
+		"grant",
+		request.GetPrincipal().GetId(),
+		request.GetEntitlement().GetId(),
+		err,
+	)
+	p.notifier.notify(
+		ctx,
+		"grant",
+		request.GetPrincipal().GetId(),
+		request.GetEntitlement().GetId(),
+		err,
+	)
+	return response, err
+}
+
+func (p *provisioningServer) Revoke(
+	ctx context.Context,
+	request *v2.GrantManagerServiceRevokeRequest,
+) (*v2.GrantManagerServiceRevokeResponse, error) {
+	response, err := p.ConnectorServer.Revoke(ctx, request)
+	p.audit.record(
+		ctx,
+		"revoke",
+		request.GetGrant().GetPrincipal().GetId(),
+		request.GetGrant().GetEntitlement().GetId(),
+		err,
+	)
+	p.notifier.notify(
+		ctx,
+		"revoke",
+		request.GetGrant().GetPrincipal().GetId(),
+		request.GetGrant().GetEntitlement().GetId(),
+		err,
+	)
+	return response, err
+}
+
+func (p *provisioningServer) DeleteResource(
+	ctx context.Context,
+	request *v2.DeleteResourceRequest,
+) (*v2.DeleteResourceResponse, error) {
+	response, err := p.ConnectorServer.DeleteResource(ctx, request)
+	p.audit.record(ctx, "delete", request.GetResourceId(), "", err)
+	p.notifier.notify(ctx, "delete", request.GetResourceId(), "", err)
+	return response, err
+}
+
+func (p *provisioningServer) CreateAccount(
+	ctx context.Context,
+	request *v2.CreateAccountRequest,
+) (*v2.CreateAccountResponse, error) {
+	response, err := p.ConnectorServer.CreateAccount(ctx, request)
+	p.notifier.notify(
+		ctx,
+		"create account",
+		nil,
+		request.GetAccountInfo().GetLogin(),
+		err,
+	)
+	return response, err
+}
@@ -0,0 +1,246 @@
+package connector
+
+import (
+	"context"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Slack rate limit tiers, as the minimum number of requests per minute Slack
+// allows for every method of the tier.
+const (
+	rateLimitTier2 = 20
+	rateLimitTier3 = 50
+	rateLimitTier4 = 100
+)
+
+// methodTiers maps the Web API methods the connector calls to their rate
+// limit tier. Methods that aren't listed are limited as tier 3.
+var methodTiers = map[string]int{
+	"admin.analytics.getFile":              rateLimitTier2,
+	"admin.apps.approve":                   rateLimitTier2,
+	"admin.apps.approved.list":             rateLimitTier2,
+	"admin.apps.requests.list":             rateLimitTier2,
+	"admin.apps.restrict":                  rateLimitTier2,
+	"admin.auth.policy.assignEntities":     rateLimitTier2,
+	"admin.auth.policy.getEntities":        rateLimitTier2,
+	"admin.auth.policy.removeEntities":     rateLimitTier2,
+	"admin.conversations.archive":          rateLimitTier2,
+	"admin.conversations.create":           rateLimitTier2,
+	"admin.conversations.disconnectShared": rateLimitTier2,
+	"admin.conversations.getTeams":         rateLimitTier3,
+	"admin.conversations.rename":           rateLimitTier2,
+	"admin.conversations.search":           rateLimitTier2,
+	"admin.roles.addAssignments":           rateLimitTier2,
+	"admin.roles.listAssignments":          rateLimitTier2,
+	"admin.roles.removeAssignments":        rateLimitTier2,
+	"admin.teams.list":                     rateLimitTier2,
+	"admin.usergroups.addTeams":            rateLimitTier2,
+	"admin.usergroups.listChannels":        rateLimitTier2,
+	"admin.usergroups.listUsers":           rateLimitTier2,
+	"admin.users.assign":                   rateLimitTier2,
+	"admin.users.invite":                   rateLimitTier2,
+	"admin.users.list":                     rateLimitTier2,
+	"admin.users.remove":                   rateLimitTier2,
+	"admin.users.setExpiration":            rateLimitTier2,
+	"admin.users.session.clearSettings":    rateLimitTier2,
+	"admin.users.session.reset":            rateLimitTier2,
+	"admin.users.setAdmin":                 rateLimitTier2,
+	"admin.users.setOwner":                 rateLimitTier2,
+	"admin.users.setRegular":               rateLimitTier2,
+	"auth.teams.list":                      rateLimitTier2,
+	"auth.test":                            rateLimitTier4,
+	"chat.postMessage":                     rateLimitTier4,
+	"conversations.approveSharedInvite":    rateLimitTier2,
+	"conversations.archive":                rateLimitTier2,
+	"conversations.create":                 rateLimitTier2,
+	"conversations.declineSharedInvite":    rateLimitTier2,
+	"conversations.inviteShared":           rateLimitTier2,
+	"conversations.list":                   rateLimitTier2,
+	"conversations.members":                rateLimitTier4,
+	"conversations.rename":                 rateLimitTier2,
+	"conversations.setPurpose":             rateLimitTier2,
+	"conversations.setTopic":               rateLimitTier2,
+	"team.accessLogs":                      rateLimitTier2,
+	"team.info":                            rateLimitTier3,
+	"usergroups.list":                      rateLimitTier2,
+	"usergroups.users.list":                rateLimitTier2,
+	"usergroups.users.update":              rateLimitTier2,
+	"users.getPresence":                    rateLimitTier3,
+	"users.info":                           rateLimitTier4,
+	"users.list":                           rateLimitTier2,
+	"users.profile.set":                    rateLimitTier3,
+}
+
+// apiMethod returns the Web API method a request path calls, or an empty
+// string for the SCIM and audit log APIs, which aren't tiered.
+func apiMethod(path string) string {
+	i := strings.LastIndex(path, "/api/")
+	if i < 0 {
+		return ""
+	}
+	return path[i+len("/api/"):]
+}
+
+// rateLimitBucket returns the token bucket a request to the given method is
+// counted against. Slack applies its tiers per app, per workspace and per
+// method, so requests are bucketed by their token, which is specific to the
+// app and the workspace or organization it was installed in, by their
+// team_id, which picks the workspace an organization token acts on, and by
+// their method.
+func rateLimitBucket(req *http.Request, method string) string {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	teamID := req.URL.Query().Get("team_id")
+
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") && req.GetBody != nil {
+		if form := readForm(req); form != nil {
+			if token == "" {
+				token = form.Get("token")
+			}
+			if teamID == "" {
+				teamID = form.Get("team_id")
+			}
+		}
+	}
+
+	// The bucket key is kept in memory only, but there is no reason to hold
+	// on to the token itself.
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(token))
+	return strconv.FormatUint(hash.Sum64(), 16) + "/" + teamID + "/" + method
+}
+
+// readForm parses a copy of a form encoded request body, leaving the body
+// itself untouched.
+func readForm(req *http.Request) url.Values {
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil
+	}
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		return nil
+	}
+	return form
+}
+
+// priorityShare is the share of every bucket that only provisioning requests
+// may use.
+const priorityShare = 0.25
+
+// rateLimiter spaces out the requests made by the whole process so that they
+// stay within the tier of every method, however many builders run at once.
+// Each bucket, see rateLimitBucket, holds up to a quarter of the per-minute
+// limit of its method, so that short bursts go through without waiting.
+//
+// Provisioning requests go ahead of sync requests: they reserve tokens in
+// advance and queue among themselves, while sync requests only take a token
+// once one is available beyond the share kept for provisioning. A sync
+// request therefore never holds a reservation a grant or revoke has to wait
+// behind.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	// perSecond is the refill rate.
+	perSecond float64
+	updated   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// wait blocks until a request to the given method, counted against the given
+// bucket, is allowed. priority is set for requests made by provisioning
+// operations.
+func (r *rateLimiter) wait(ctx context.Context, bucket string, method string, priority bool) error {
+	if r == nil || method == "" {
+		return nil
+	}
+
+	for {
+		delay, reserved := r.reserve(bucket, method, priority, time.Now())
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		if reserved {
+			return nil
+		}
+	}
+}
+
+// reserve returns how long the request has to wait, and whether a token was
+// taken for it. Priority requests always take a token: tokens can go
+// negative, which queues them in the order they were reserved. Other requests
+// only take a token that is available right away without dipping into the
+// priority share, and otherwise have to try again after the returned delay.
+func (r *rateLimiter) reserve(key string, method string, priority bool, now time.Time) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bucket, ok := r.buckets[key]
+	if !ok {
+		perMinute, ok := methodTiers[method]
+		if !ok {
+			perMinute = rateLimitTier3
+		}
+		capacity := float64(perMinute) / 4
+		bucket = &tokenBucket{
+			capacity:  capacity,
+			tokens:    capacity,
+			perSecond: float64(perMinute) / 60,
+			updated:   now,
+		}
+		r.buckets[key] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.updated).Seconds() * bucket.perSecond
+	if bucket.tokens > bucket.capacity {
+		bucket.tokens = bucket.capacity
+	}
+	bucket.updated = now
+
+	if priority {
+		bucket.tokens--
+		if bucket.tokens >= 0 {
+			return 0, true
+		}
+		return time.Duration(-bucket.tokens / bucket.perSecond * float64(time.Second)), true
+	}
+
+	// A bucket smaller than one token plus the priority share, i.e. a tier
+	// with fewer than 5 requests per minute, is only shared when full.
+	floor := min(bucket.capacity*priorityShare, bucket.capacity-1)
+	if bucket.tokens-1 >= floor {
+		bucket.tokens--
+		return 0, true
+	}
+	return time.Duration((floor + 1 - bucket.tokens) / bucket.perSecond * float64(time.Second)), false
+}
@@ -0,0 +1,65 @@
+package connector
+
+import (
+	"context"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errReadOnly is returned by every operation that would change Slack while
+// the connector runs in read-only mode.
+var errReadOnly = status.Error(
+	codes.FailedPrecondition,
+	"baton-slack: the connector is running in read-only mode, provisioning is disabled",
+)
+
+// readOnlyServer rejects every provisioning operation, whether or not
+// provisioning was enabled, so that a sync-only deployment can't change Slack.
+type readOnlyServer struct {
+	types.ConnectorServer
+}
+
+func (r *readOnlyServer) Grant(
+	_ context.Context,
+	_ *v2.GrantManagerServiceGrantRequest,
+) (*v2.GrantManagerServiceGrantResponse, error) {
+	return nil, errReadOnly
+}
+
+func (r *readOnlyServer) Revoke(
+	_ context.Context,
+	_ *v2.GrantManagerServiceRevokeRequest,
+) (*v2.GrantManagerServiceRevokeResponse, error) {
+	return nil, errReadOnly
+}
+
+func (r *readOnlyServer) CreateAccount(
+	_ context.Context,
+	_ *v2.CreateAccountRequest,
+) (*v2.CreateAccountResponse, error) {
+	return nil, errReadOnly
+}
+
+func (r *readOnlyServer) CreateResource(
+	_ context.Context,
+	_ *v2.CreateResourceRequest,
+) (*v2.CreateResourceResponse, error) {
+	return nil, errReadOnly
+}
+
+func (r *readOnlyServer) DeleteResource(
+	_ context.Context,
+	_ *v2.DeleteResourceRequest,
+) (*v2.DeleteResourceResponse, error) {
+	return nil, errReadOnly
+}
+
+func (r *readOnlyServer) RotateCredential(
+	_ context.Context,
+	_ *v2.RotateCredentialRequest,
+) (*v2.RotateCredentialResponse, error) {
+	return nil, errReadOnly
+}
@@ -45,4 +45,39 @@ var (
 			v2.ResourceType_TRAIT_ROLE,
 		},
 	}
+	resourceTypeExternalOrganization = &v2.ResourceType{
+		Id:          "externalOrganization",
+		DisplayName: "External Organization",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_GROUP,
+		},
+	}
+	resourceTypeChannel = &v2.ResourceType{
+		Id:          "channel",
+		DisplayName: "Channel",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_GROUP,
+		},
+	}
+	resourceTypeSharedChannel = &v2.ResourceType{
+		Id:          "sharedChannel",
+		DisplayName: "Slack Connect Channel",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_GROUP,
+		},
+	}
+	resourceTypeApp = &v2.ResourceType{
+		Id:          "app",
+		DisplayName: "App",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_APP,
+		},
+	}
+	resourceTypeAuthPolicy = &v2.ResourceType{
+		Id:          "authPolicy",
+		DisplayName: "Authentication Policy",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_ROLE,
+		},
+	}
 )
@@ -0,0 +1,424 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// maxRetryAttempts bounds how many times a queued operation is retried
+	// before it is dropped.
+	maxRetryAttempts = 8
+	// retryBaseDelay is the delay before the first retry. It doubles with
+	// every attempt, up to retryMaxDelay.
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+	// retryPollInterval is how often the queue is checked for due retries.
+	retryPollInterval = 10 * time.Second
+)
+
+// Slack error codes reporting a failure on Slack's side that is worth
+// retrying.
+var transientErrorCodes = map[string]bool{
+	"ratelimited":         true,
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+}
+
+// isTransient reports whether an operation that failed with err may succeed
+// if it is repeated later.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var rateLimitErr *slack.RateLimitedError
+	if errors.As(err, &rateLimitErr) {
+		return true
+	}
+
+	var statusCodeErr slack.StatusCodeError
+	if errors.As(err, &statusCodeErr) {
+		return statusCodeErr.Code == http.StatusTooManyRequests || statusCodeErr.Code >= 500
+	}
+
+	var scimErr *enterprise.SCIMError
+	if errors.As(err, &scimErr) {
+		return scimErr.StatusCode() == http.StatusTooManyRequests || scimErr.StatusCode() >= 500
+	}
+
+	if transientErrorCodes[slackErrorCode(err)] {
+		return true
+	}
+
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return true
+		}
+	}
+	return false
+}
+
+// retryEntry is a grant or revoke waiting to be retried.
+type retryEntry struct {
+	Operation   string          `json:"operation"`
+	Request     json.RawMessage `json:"request"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error"`
+}
+
+// retryQueue persists the grants and revokes that failed with a transient
+// error and retries them with exponential backoff, so that a rate limit or a
+// Slack outage doesn't fail the provisioning task outright.
+type retryQueue struct {
+	path string
+	// server runs the retried operations. It is set by WrapServer.
+	server types.ConnectorServer
+
+	mu      sync.Mutex
+	entries map[string]*retryEntry
+}
+
+// loadRetryQueue reads the queue persisted at the given path, if any. An
+// empty path disables the queue.
+func loadRetryQueue(path string) (*retryQueue, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	q := &retryQueue{
+		path:    path,
+		entries: make(map[string]*retryEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("baton-slack: error reading retry queue: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &q.entries); err != nil {
+		return nil, fmt.Errorf("baton-slack: error parsing retry queue: %w", err)
+	}
+	return q, nil
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// enqueue stores a failed operation for retry. Queuing the same operation
+// again keeps its attempt count, while queuing the opposite operation for the
+// same entitlement and principal replaces it. It returns the time of the next
+// attempt.
+func (q *retryQueue) enqueue(key string, operation string, request json.RawMessage, opErr error) (time.Time, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, ok := q.entries[key]
+	if !ok || entry.Operation != operation {
+		entry = &retryEntry{
+			Operation: operation,
+			Request:   request,
+		}
+		q.entries[key] = entry
+	}
+	entry.Attempts++
+	entry.NextAttempt = time.Now().Add(retryDelay(entry.Attempts))
+	entry.LastError = opErr.Error()
+	return entry.NextAttempt, q.write()
+}
+
+// drop removes the entry queued under key if it is one of the given
+// operations.
+func (q *retryQueue) drop(ctx context.Context, key string, operations ...string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, ok := q.entries[key]
+	if !ok || !slices.Contains(operations, entry.Operation) {
+		return
+	}
+
+	delete(q.entries, key)
+	if err := q.write(); err != nil {
+		ctxzap.Extract(ctx).Error("baton-slack: error writing retry queue", zap.Error(err))
+	}
+}
+
+// due returns the keys of the entries whose next attempt is due.
+func (q *retryQueue) due(now time.Time) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var keys []string
+	for key, entry := range q.entries {
+		if !entry.NextAttempt.After(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// retry runs a queued operation once more. It is removed from the queue when
+// it succeeds, fails for good, or runs out of attempts.
+func (q *retryQueue) retry(ctx context.Context, key string) {
+	q.mu.Lock()
+	entry, ok := q.entries[key]
+	if !ok {
+		q.mu.Unlock()
+		return
+	}
+	operation, request := entry.Operation, entry.Request
+	q.mu.Unlock()
+
+	err := q.run(ctx, operation, request)
+
+	logger := ctxzap.Extract(ctx).With(
+		zap.String("operation", operation),
+		zap.String("key", key),
+	)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// A newer grant or revoke of the same entitlement replaced or dropped the
+	// entry while it was running.
+	if q.entries[key] != entry {
+		return
+	}
+
+	switch {
+	case err == nil:
+		logger.Info("baton-slack: queued operation succeeded")
+		delete(q.entries, key)
+	case !isTransient(err):
+		logger.Error("baton-slack: queued operation failed, dropping it", zap.Error(err))
+		delete(q.entries, key)
+	case entry.Attempts >= maxRetryAttempts:
+		logger.Error("baton-slack: queued operation ran out of attempts, dropping it", zap.Error(err))
+		delete(q.entries, key)
+	default:
+		entry.Attempts++
+		entry.NextAttempt = time.Now().Add(retryDelay(entry.Attempts))
+		entry.LastError = err.Error()
+		logger.Warn("baton-slack: queued operation failed, will retry", zap.Error(err), zap.Time("next_attempt", entry.NextAttempt))
+	}
+
+	if err := q.write(); err != nil {
+		logger.Error("baton-slack: error writing retry queue", zap.Error(err))
+	}
+}
+
+func (q *retryQueue) run(ctx context.Context, operation string, request json.RawMessage) error {
+	switch operation {
+	case "grant":
+		req := &v2.GrantManagerServiceGrantRequest{}
+		if err := protojson.Unmarshal(request, req); err != nil {
+			return err
+		}
+		_, err := q.server.Grant(ctx, req)
+		return err
+	case "revoke":
+		req := &v2.GrantManagerServiceRevokeRequest{}
+		if err := protojson.Unmarshal(request, req); err != nil {
+			return err
+		}
+		_, err := q.server.Revoke(ctx, req)
+		return err
+	default:
+		return fmt.Errorf("baton-slack: unknown queued operation %q", operation)
+	}
+}
+
+// write replaces the queue file atomically, like the checkpoint file. It must
+// be called with the lock held.
+func (q *retryQueue) write() error {
+	data, err := json.Marshal(q.entries)
+	if err != nil {
+		return fmt.Errorf("baton-slack: error encoding retry queue: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".*")
+	if err != nil {
+		return fmt.Errorf("baton-slack: error writing retry queue: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("baton-slack: error writing retry queue: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("baton-slack: error writing retry queue: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return fmt.Errorf("baton-slack: error writing retry queue: %w", err)
+	}
+	return nil
+}
+
+// RunRetryQueue retries the queued operations until the context is done. It
+// is a no-op unless WithRetryQueueFile was given, and must be started after
+// WrapServer.
+func (s *Slack) RunRetryQueue(ctx context.Context) error {
+	if s.retries == nil || s.retries.server == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(retryPollInterval)
+	defer ticker.Stop()
+	for {
+		for _, key := range s.retries.due(time.Now()) {
+			s.retries.retry(ctx, key)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// retryServer queues grants and revokes that fail with a transient error
+// instead of only failing them.
+type retryServer struct {
+	types.ConnectorServer
+	queue *retryQueue
+}
+
+// retryKey identifies the entitlement and principal of a grant or revoke, so
+// that a grant and a later revoke of the same access share a queue entry.
+func retryKey(entitlement *v2.Entitlement, principal *v2.Resource) string {
+	return fmt.Sprintf(
+		"%s/%s/%s",
+		entitlement.GetId(),
+		principal.GetId().GetResourceType(),
+		principal.GetId().GetResource(),
+	)
+}
+
+// queued records a failed operation and returns the annotations of the
+// successful response reported to the caller, which mark the operation as
+// queued for retry. The operation isn't reported as failed, so that the task
+// isn't retried by the caller on top of the queue. The original error is
+// returned if the operation couldn't be queued.
+func (r *retryServer) queued(
+	ctx context.Context,
+	operation string,
+	key string,
+	request json.RawMessage,
+	opErr error,
+) (annotations.Annotations, error) {
+	nextAttempt, err := r.queue.enqueue(key, operation, request, opErr)
+	if err != nil {
+		ctxzap.Extract(ctx).Error("baton-slack: error queuing operation for retry", zap.Error(err))
+		return nil, opErr
+	}
+	ctxzap.Extract(ctx).Warn(
+		"baton-slack: operation failed with a transient error, queued for retry",
+		zap.String("operation", operation),
+		zap.String("key", key),
+		zap.Error(opErr),
+	)
+
+	annos := annotations.New()
+	annos.Append(&structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"retry_queued": structpb.NewBoolValue(true),
+			"operation":    structpb.NewStringValue(operation),
+			"next_attempt": structpb.NewStringValue(nextAttempt.UTC().Format(time.RFC3339)),
+			"last_error":   structpb.NewStringValue(opErr.Error()),
+		},
+	})
+	return annos, nil
+}
+
+func (r *retryServer) Grant(
+	ctx context.Context,
+	request *v2.GrantManagerServiceGrantRequest,
+) (*v2.GrantManagerServiceGrantResponse, error) {
+	key := retryKey(request.GetEntitlement(), request.GetPrincipal())
+	// A queued revoke is stale once the access is granted again.
+	r.queue.drop(ctx, key, "revoke")
+
+	response, err := r.ConnectorServer.Grant(ctx, request)
+	if err == nil {
+		r.queue.drop(ctx, key, "grant")
+	}
+	if !isTransient(err) {
+		return response, err
+	}
+
+	data, marshalErr := protojson.Marshal(request)
+	if marshalErr != nil {
+		return response, err
+	}
+	annos, err := r.queued(ctx, "grant", key, data, err)
+	if err != nil {
+		return response, err
+	}
+	return &v2.GrantManagerServiceGrantResponse{Annotations: annos}, nil
+}
+
+func (r *retryServer) Revoke(
+	ctx context.Context,
+	request *v2.GrantManagerServiceRevokeRequest,
+) (*v2.GrantManagerServiceRevokeResponse, error) {
+	key := retryKey(request.GetGrant().GetEntitlement(), request.GetGrant().GetPrincipal())
+	// A queued grant is stale once the access is revoked again.
+	r.queue.drop(ctx, key, "grant")
+
+	response, err := r.ConnectorServer.Revoke(ctx, request)
+	if err == nil {
+		r.queue.drop(ctx, key, "revoke")
+	}
+	if !isTransient(err) {
+		return response, err
+	}
+
+	data, marshalErr := protojson.Marshal(request)
+	if marshalErr != nil {
+		return response, err
+	}
+	annos, err := r.queued(ctx, "revoke", key, data, err)
+	if err != nil {
+		return response, err
+	}
+	return &v2.GrantManagerServiceRevokeResponse{Annotations: annos}, nil
+}
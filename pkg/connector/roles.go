@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"maps"
 	"slices"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -42,21 +43,72 @@ var roles = map[string]string{
 	MemberRoleID:             "Member",
 }
 
+// Role emission policies, see WithRoleEmission.
+const (
+	RoleEmissionAll     = "all"
+	RoleEmissionHighest = "highest"
+)
+
+// workspaceRolePriority orders workspace roles from the most to the least
+// privileged.
+var workspaceRolePriority = []string{
+	PrimaryOwnerRoleID,
+	OwnerRoleID,
+	AdminRoleID,
+	MemberRoleID,
+	MultiChannelGuestRoleID,
+	SingleChannelGuestRoleID,
+	InvitedMemberRoleID,
+	BotRoleID,
+}
+
+// highestRole returns the most privileged of the given role IDs according to
+// priority, or all of them if highestOnly is false.
+func highestRole(roleIDs []string, priority []string, highestOnly bool) []string {
+	if !highestOnly || len(roleIDs) < 2 {
+		return roleIDs
+	}
+
+	for _, roleID := range priority {
+		if slices.Contains(roleIDs, roleID) {
+			return []string{roleID}
+		}
+	}
+	return roleIDs[:1]
+}
+
 type workspaceRoleType struct {
-	resourceType     *v2.ResourceType
-	client           *slack.Client
-	enterpriseClient *enterprise.Client
+	resourceType      *v2.ResourceType
+	client            *slack.Client
+	enterpriseClient  *enterprise.Client
+	cache             *syncCache
+	ssoEnabled        bool
+	verifyBeforeWrite bool
+	// guestExpiration is how long guest access granted through the guest
+	// roles lasts. Zero leaves the expiration of the guest unchanged.
+	guestExpiration time.Duration
 }
 
 func (o *workspaceRoleType) ResourceType(_ context.Context) *v2.ResourceType {
 	return o.resourceType
 }
 
-func workspaceRoleBuilder(client *slack.Client, enterpriseClient *enterprise.Client) *workspaceRoleType {
+func workspaceRoleBuilder(
+	client *slack.Client,
+	enterpriseClient *enterprise.Client,
+	cache *syncCache,
+	ssoEnabled bool,
+	verifyBeforeWrite bool,
+	guestExpiration time.Duration,
+) *workspaceRoleType {
 	return &workspaceRoleType{
-		resourceType:     resourceTypeWorkspaceRole,
-		client:           client,
-		enterpriseClient: enterpriseClient,
+		resourceType:      resourceTypeWorkspaceRole,
+		client:            client,
+		enterpriseClient:  enterpriseClient,
+		cache:             cache,
+		ssoEnabled:        ssoEnabled,
+		verifyBeforeWrite: verifyBeforeWrite,
+		guestExpiration:   guestExpiration,
 	}
 }
 
@@ -112,7 +164,7 @@ func (o *workspaceRoleType) List(
 }
 
 func (o *workspaceRoleType) Entitlements(
-	_ context.Context,
+	ctx context.Context,
 	resource *v2.Resource,
 	_ *pagination.Token,
 ) (
@@ -121,9 +173,10 @@ func (o *workspaceRoleType) Entitlements(
 	annotations.Annotations,
 	error,
 ) {
-	workspaceName, ok := workspacesNameCache[resource.ParentResourceId.Resource]
-	if !ok {
-		return nil, "", nil, fmt.Errorf("invalid workspace: %s", resource.ParentResourceId.Resource)
+	workspaceName, err := o.cache.workspaceName(ctx, o.client, resource.ParentResourceId.Resource)
+	if err != nil {
+		annos, err := pkg.AnnotationsForError(err)
+		return nil, "", annos, err
 	}
 	return []*v2.Entitlement{
 			entitlement.NewAssignmentEntitlement(
@@ -195,6 +248,22 @@ func (o *workspaceRoleType) Grant(
 	}
 
 	outputAnnotations := annotations.New()
+	roleID, _ := pkg.ParseID(entitlement.Resource.Id.Resource)
+	if isGuestRole(roleID) {
+		return o.grantGuestRole(ctx, outputAnnotations, teamID, principal.Id.Resource, roleID)
+	}
+
+	done, err := alreadyInState(
+		ctx,
+		o.verifyBeforeWrite && isAdminRole(roleID),
+		outputAnnotations,
+		true,
+		hasWorkspaceRole(o.enterpriseClient, teamID, principal.Id.Resource, roleID),
+	)
+	if err != nil || done {
+		return outputAnnotations, err
+	}
+
 	ratelimitData, err := o.enterpriseClient.SetWorkspaceRole(
 		ctx,
 		teamID,
@@ -202,11 +271,7 @@ func (o *workspaceRoleType) Grant(
 		entitlement.Resource.Id.Resource,
 	)
 	outputAnnotations.WithRateLimiting(ratelimitData)
-	if err != nil {
-		return outputAnnotations, fmt.Errorf("baton-slack: failed to assign user role: %w", err)
-	}
-
-	return outputAnnotations, nil
+	return grantResult(outputAnnotations, err, "failed to assign user role")
 }
 
 func (o *workspaceRoleType) Revoke(
@@ -236,6 +301,24 @@ func (o *workspaceRoleType) Revoke(
 	}
 
 	outputAnnotations := annotations.New()
+	roleID, _ := pkg.ParseID(grant.Entitlement.Resource.Id.Resource)
+	if roleID == InvitedMemberRoleID {
+		return o.cancelInvitation(ctx, outputAnnotations, teamID, principal.Id.Resource)
+	}
+	if isGuestRole(roleID) {
+		return o.revokeGuestRole(ctx, outputAnnotations, teamID, principal.Id.Resource, roleID)
+	}
+
+	done, err := alreadyInState(
+		ctx,
+		o.verifyBeforeWrite && isAdminRole(roleID),
+		outputAnnotations,
+		false,
+		hasWorkspaceRole(o.enterpriseClient, teamID, principal.Id.Resource, roleID),
+	)
+	if err != nil || done {
+		return outputAnnotations, err
+	}
 
 	// empty role type means regular user
 	ratelimitData, err := o.enterpriseClient.SetWorkspaceRole(
@@ -245,10 +328,113 @@ func (o *workspaceRoleType) Revoke(
 		"",
 	)
 	outputAnnotations.WithRateLimiting(ratelimitData)
+	return revokeResult(outputAnnotations, err, "failed to revoke user role")
+}
+
+// grantGuestRole sets the expiration of a guest. Slack doesn't provide an API
+// to turn a member into a guest, so the user has to be a guest of that kind
+// already, e.g. after the invite_guest action.
+func (o *workspaceRoleType) grantGuestRole(
+	ctx context.Context,
+	outputAnnotations annotations.Annotations,
+	teamID string,
+	userID string,
+	roleID string,
+) (
+	annotations.Annotations,
+	error,
+) {
+	isGuest, ratelimitData, err := hasWorkspaceRole(o.enterpriseClient, teamID, userID, roleID)(ctx)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return outputAnnotations, err
+	}
+	if !isGuest {
+		return outputAnnotations, fmt.Errorf(
+			"baton-slack: %s is not a %s of the workspace, Slack doesn't provide an API to turn a member into a guest",
+			userID,
+			roles[roleID],
+		)
+	}
+
+	if o.guestExpiration == 0 {
+		outputAnnotations.Append(&v2.GrantAlreadyExists{})
+		return outputAnnotations, nil
+	}
+
+	expiresAt := time.Now().Add(o.guestExpiration).Unix()
+	ratelimitData, err = o.enterpriseClient.SetUserExpiration(ctx, teamID, userID, expiresAt)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	return grantResult(outputAnnotations, err, "failed to set guest expiration")
+}
 
+// revokeGuestRole ends guest access by removing the guest from the
+// workspace. admin.users.setRegular would promote the guest to a full member
+// instead, so it is never used for guest roles.
+func (o *workspaceRoleType) revokeGuestRole(
+	ctx context.Context,
+	outputAnnotations annotations.Annotations,
+	teamID string,
+	userID string,
+	roleID string,
+) (
+	annotations.Annotations,
+	error,
+) {
+	isGuest, ratelimitData, err := hasWorkspaceRole(o.enterpriseClient, teamID, userID, roleID)(ctx)
+	outputAnnotations.WithRateLimiting(ratelimitData)
 	if err != nil {
-		return outputAnnotations, fmt.Errorf("baton-slack: failed to revoke user role: %w", err)
+		return outputAnnotations, err
 	}
+	if !isGuest {
+		outputAnnotations.Append(&v2.GrantAlreadyRevoked{})
+		return outputAnnotations, nil
+	}
+
+	ratelimitData, err = o.enterpriseClient.RemoveUserFromTeam(ctx, teamID, userID)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil && notMemberCodes[slackErrorCode(err)] {
+		outputAnnotations.Append(&v2.GrantAlreadyRevoked{})
+		return outputAnnotations, nil
+	}
+	return revokeResult(outputAnnotations, err, "failed to remove guest from workspace")
+}
+
+// cancelInvitation revokes the invited member role by cancelling the pending
+// invitation: the invited account is deleted through SCIM when available, or
+// removed from the workspace otherwise.
+func (o *workspaceRoleType) cancelInvitation(
+	ctx context.Context,
+	outputAnnotations annotations.Annotations,
+	teamID string,
+	userID string,
+) (
+	annotations.Annotations,
+	error,
+) {
+	var (
+		ratelimitData *v2.RateLimitDescription
+		err           error
+	)
+	if o.ssoEnabled {
+		ratelimitData, err = o.enterpriseClient.DeactivateUser(ctx, userID)
+	} else {
+		ratelimitData, err = o.enterpriseClient.RemoveUserFromTeam(ctx, teamID, userID)
+	}
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil && notMemberCodes[slackErrorCode(err)] {
+		outputAnnotations.Append(&v2.GrantAlreadyRevoked{})
+		return outputAnnotations, nil
+	}
+	return revokeResult(outputAnnotations, err, "failed to cancel invitation")
+}
+
+// isAdminRole reports whether the role is one of the workspace roles set with
+// admin.users.setOwner and admin.users.setAdmin.
+func isAdminRole(roleID string) bool {
+	return roleID == OwnerRoleID || roleID == AdminRoleID
+}
 
-	return outputAnnotations, nil
+func isGuestRole(roleID string) bool {
+	return roleID == MultiChannelGuestRoleID || roleID == SingleChannelGuestRoleID
 }
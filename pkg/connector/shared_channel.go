@@ -0,0 +1,407 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	resources "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-slack/pkg"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// sharedChannelResourceType syncs the channels a workspace shares with other
+// workspaces or organizations, with their members, so that cross-org access
+// can be audited.
+type sharedChannelResourceType struct {
+	resourceType     *v2.ResourceType
+	client           *slack.Client
+	enterpriseID     string
+	enterpriseClient *enterprise.Client
+	cache            *syncCache
+	// channels selects the shared channels that are synced.
+	channels channelFilter
+}
+
+func (o *sharedChannelResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func sharedChannelBuilder(
+	client *slack.Client,
+	enterpriseID string,
+	enterpriseClient *enterprise.Client,
+	channels channelFilter,
+	cache *syncCache,
+) *sharedChannelResourceType {
+	return &sharedChannelResourceType{
+		resourceType:     resourceTypeSharedChannel,
+		client:           client,
+		enterpriseID:     enterpriseID,
+		enterpriseClient: enterpriseClient,
+		cache:            cache,
+		channels:         channels,
+	}
+}
+
+// sharedChannelResource creates a resource for a shared channel, with the
+// same ID and placement as its channel resource.
+func sharedChannelResource(
+	_ context.Context,
+	channel slack.Channel,
+	placement channelPlacement,
+) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"channel_id":         channel.ID,
+		"channel_name":       channel.Name,
+		"is_private":         channel.IsPrivate,
+		"is_ext_shared":      channel.IsExtShared,
+		"connected_team_ids": strings.Join(connectedTeamIDs(channel), ","),
+	}
+	if len(placement.teamIDs) > 0 {
+		profile["team_ids"] = teamIDValues(placement.teamIDs)
+	}
+
+	return resources.NewGroupResource(
+		channel.Name,
+		resourceTypeSharedChannel,
+		channelResourceID(placement.teamID, channel.ID),
+		[]resources.GroupTraitOption{
+			resources.WithGroupProfile(profile),
+		},
+		resources.WithParentResourceID(placement.parentResourceID),
+		resources.WithAnnotation(channelLink(placement.teamID, channel.ID)),
+	)
+}
+
+func (o *sharedChannelResourceType) List(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	pt *pagination.Token,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	bag, err := pkg.ParsePageToken(pt.Token, &v2.ResourceId{ResourceType: resourceTypeSharedChannel.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	channels, nextCursor, err := o.client.GetConversationsContext(
+		ctx,
+		&slack.GetConversationsParameters{
+			Cursor:          bag.PageToken(),
+			ExcludeArchived: true,
+			Limit:           conversationsPageSize,
+			Types:           []string{"public_channel", "private_channel"},
+			TeamID:          parentResourceID.Resource,
+		},
+	)
+	if err != nil {
+		annos, err := pkg.AnnotationsForError(err)
+		return nil, "", annos, err
+	}
+
+	pageToken, err := pkg.NextPageToken(bag, "channels", nextCursor)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	outputAnnotations := annotations.New()
+	var rv []*v2.Resource
+	for _, channel := range channels {
+		if !channel.IsShared && !channel.IsExtShared {
+			continue
+		}
+		if !o.channels.matches(channel.Name) {
+			continue
+		}
+
+		placement, ok, err := placeChannel(ctx, o.enterpriseID, o.enterpriseClient, o.cache, channel, parentResourceID, outputAnnotations)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+		if !ok {
+			continue
+		}
+
+		r, err := sharedChannelResource(ctx, channel, placement)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, r)
+	}
+
+	return rv, pageToken, outputAnnotations, nil
+}
+
+func (o *sharedChannelResourceType) Entitlements(
+	_ context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Entitlement,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	return []*v2.Entitlement{
+			entitlement.NewAssignmentEntitlement(
+				resource,
+				memberEntitlement,
+				entitlement.WithGrantableTo(resourceTypeUser, resourceTypeExternalOrganization),
+				entitlement.WithDescription(
+					fmt.Sprintf(
+						"Member of the #%s Slack Connect channel",
+						resource.DisplayName,
+					),
+				),
+				entitlement.WithDisplayName(
+					fmt.Sprintf(
+						"#%s Channel %s",
+						resource.DisplayName,
+						memberEntitlement,
+					),
+				),
+			),
+		},
+		"",
+		nil,
+		nil
+}
+
+// Grants returns the members of a shared channel. Members of the workspace's
+// own organization are granted as users. Members of other organizations
+// aren't synced as users, so each of those organizations is granted as a
+// whole instead, with the IDs of its members in the grant metadata. That
+// requires the complete member list, so it is read eagerly.
+func (o *sharedChannelResourceType) Grants(
+	ctx context.Context,
+	resource *v2.Resource,
+	_ *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	teamID, channelID, err := parseChannelResourceID(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var memberIDs []string
+	err = pkg.ForEachPage("channel members", func(cursor string) (string, error) {
+		members, nextCursor, err := o.client.GetUsersInConversationContext(
+			ctx,
+			&slack.GetUsersInConversationParameters{
+				ChannelID: channelID,
+				Cursor:    cursor,
+				Limit:     conversationsPageSize,
+			},
+		)
+		if err != nil {
+			return "", fmt.Errorf("baton-slack: error listing channel members: %w", pkg.WrapTokenError(err))
+		}
+		memberIDs = append(memberIDs, members...)
+		return nextCursor, nil
+	})
+	if err != nil {
+		annos, err := pkg.AnnotationsForError(err)
+		return nil, "", annos, err
+	}
+
+	outputAnnotations := annotations.New()
+	users, ratelimitData, err := o.enterpriseClient.GetUsersInfoBatch(ctx, memberIDs)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	memberTeams := make(map[string]string, len(users))
+	for _, user := range users {
+		if !o.isInternal(user, teamID) {
+			memberTeams[user.ID] = user.TeamID
+		}
+	}
+
+	var rv []*v2.Grant
+	externalMembers := make(map[string][]string)
+	for _, memberID := range memberIDs {
+		if externalTeamID, ok := memberTeams[memberID]; ok {
+			externalMembers[externalTeamID] = append(externalMembers[externalTeamID], memberID)
+			continue
+		}
+
+		userID, err := resources.NewResourceID(resourceTypeUser, memberID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(
+			rv,
+			grant.NewGrant(resource, memberEntitlement, userID, withGrantSource(grantSourceConversationMembers)),
+		)
+	}
+
+	externalTeamIDs := make([]string, 0, len(externalMembers))
+	for externalTeamID := range externalMembers {
+		externalTeamIDs = append(externalTeamIDs, externalTeamID)
+	}
+	sort.Strings(externalTeamIDs)
+
+	for _, externalTeamID := range externalTeamIDs {
+		organizationID, err := resources.NewResourceID(
+			resourceTypeExternalOrganization,
+			fmt.Sprintf("%s:%s", teamID, externalTeamID),
+		)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(
+			rv,
+			grant.NewGrant(
+				resource,
+				memberEntitlement,
+				organizationID,
+				withGrantSourceMetadata(
+					grantSourceConversationMembers,
+					map[string]interface{}{
+						"external_user_ids": strings.Join(externalMembers[externalTeamID], ","),
+					},
+				),
+			),
+		)
+	}
+
+	return rv, "", outputAnnotations, nil
+}
+
+// isInternal reports whether a channel member belongs to the organization of
+// the workspace, rather than to an organization the channel is shared with.
+func (o *sharedChannelResourceType) isInternal(user enterprise.User, teamID string) bool {
+	if user.TeamID == "" || user.TeamID == teamID || o.cache.isWorkspace(user.TeamID) {
+		return true
+	}
+	return o.enterpriseID != "" && user.Enterprise.EnterpriseID == o.enterpriseID
+}
+
+// Grant adds a user to a shared channel. Members of the workspace's own
+// organization are added with conversations.invite, like on any channel.
+// Users of other organizations are sent a Slack Connect invitation with
+// conversations.inviteShared, which needs the conversations.connect:write
+// scope; they join once the invitation is accepted on their side.
+func (o *sharedChannelResourceType) Grant(
+	ctx context.Context,
+	principal *v2.Resource,
+	entitlement *v2.Entitlement,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		logger.Warn(
+			"baton-slack: only users can be invited to a shared channel",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: only users can be invited to a shared channel, grant the entitlement to the users of the organization")
+	}
+
+	teamID, channelID, err := parseChannelResourceID(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	outputAnnotations := annotations.New()
+	users, ratelimitData, err := o.enterpriseClient.GetUsersInfoBatch(ctx, []string{principal.Id.Resource})
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return outputAnnotations, err
+	}
+	if len(users) == 0 {
+		return outputAnnotations, fmt.Errorf("baton-slack: user %s not found", principal.Id.Resource)
+	}
+
+	if o.isInternal(users[0], teamID) {
+		_, err = o.client.InviteUsersToConversationContext(ctx, channelID, principal.Id.Resource)
+		return grantResult(outputAnnotations, pkg.WrapTokenError(err), "failed to add user to shared channel")
+	}
+
+	inviteID, _, err := o.client.InviteSharedUserIDsToConversationContext(ctx, channelID, principal.Id.Resource)
+	if err != nil {
+		return grantResult(outputAnnotations, pkg.WrapTokenError(err), "failed to send Slack Connect invitation")
+	}
+	logger.Info(
+		"baton-slack: sent Slack Connect invitation",
+		zap.String("channel_id", channelID),
+		zap.String("user_id", principal.Id.Resource),
+		zap.String("invite_id", inviteID),
+	)
+	return outputAnnotations, nil
+}
+
+// Revoke removes a user from a shared channel with conversations.kick. An
+// external organization is disconnected from the channel as a whole with
+// admin.conversations.disconnectShared, which removes all of its members and
+// is only available on Enterprise Grid.
+func (o *sharedChannelResourceType) Revoke(
+	ctx context.Context,
+	grant *v2.Grant,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	_, channelID, err := parseChannelResourceID(grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	principal := grant.Principal
+	switch principal.Id.ResourceType {
+	case resourceTypeUser.Id:
+		err = o.client.KickUserFromConversationContext(ctx, channelID, principal.Id.Resource)
+		return revokeResult(annotations.New(), pkg.WrapTokenError(err), "failed to remove user from shared channel")
+
+	case resourceTypeExternalOrganization.Id:
+		if o.enterpriseID == "" {
+			return nil, fmt.Errorf("baton-slack: disconnecting an organization from a shared channel requires Enterprise Grid")
+		}
+		// External organizations are scoped to the workspace, like channels.
+		_, externalTeamID, ok := strings.Cut(principal.Id.Resource, ":")
+		if !ok {
+			return nil, fmt.Errorf("baton-slack: invalid external organization ID %q", principal.Id.Resource)
+		}
+
+		outputAnnotations := annotations.New()
+		ratelimitData, err := o.enterpriseClient.DisconnectSharedChannel(ctx, channelID, []string{externalTeamID})
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		return revokeResult(outputAnnotations, err, "failed to disconnect organization from shared channel")
+
+	default:
+		logger.Warn(
+			"baton-slack: only users and external organizations can be removed from a shared channel",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: only users and external organizations can be removed from a shared channel")
+	}
+}
@@ -0,0 +1,254 @@
+package connector
+
+import (
+	"context"
+	"sync"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// syncCounts are the counts kept for a sync operation and, summed up, for a
+// resource type over the whole sync.
+type syncCounts struct {
+	Resources    int
+	Entitlements int
+	Grants       int
+	Skipped      int
+	Errors       int
+}
+
+func (c *syncCounts) add(other syncCounts) {
+	c.Resources += other.Resources
+	c.Entitlements += other.Entitlements
+	c.Grants += other.Grants
+	c.Skipped += other.Skipped
+	c.Errors += other.Errors
+}
+
+func (c syncCounts) fields(prefix string) []zap.Field {
+	return []zap.Field{
+		zap.Int(prefix+"resources", c.Resources),
+		zap.Int(prefix+"entitlements", c.Entitlements),
+		zap.Int(prefix+"grants", c.Grants),
+		zap.Int(prefix+"skipped", c.Skipped),
+		zap.Int(prefix+"errors", c.Errors),
+	}
+}
+
+func (c syncCounts) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"resources":    c.Resources,
+		"entitlements": c.Entitlements,
+		"grants":       c.Grants,
+		"skipped":      c.Skipped,
+		"errors":       c.Errors,
+	}
+}
+
+type skippedCounterKey struct{}
+
+// recordSkipped counts resources a builder deliberately left out of the
+// current page.
+func recordSkipped(ctx context.Context, n int) {
+	if counter, ok := ctx.Value(skippedCounterKey{}).(*int); ok {
+		*counter += n
+	}
+}
+
+// syncStats keeps the counts of the operations of the current sync. A sync
+// operation lists one resource type under a parent, or the entitlements or
+// grants of one resource, and usually spans several pages.
+type syncStats struct {
+	annotate bool
+
+	mu     sync.Mutex
+	ops    map[string]*syncCounts
+	totals map[string]*syncCounts
+}
+
+func newSyncStats(annotate bool) *syncStats {
+	return &syncStats{
+		annotate: annotate,
+		ops:      make(map[string]*syncCounts),
+		totals:   make(map[string]*syncCounts),
+	}
+}
+
+// reset starts counting a new sync.
+func (s *syncStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops = make(map[string]*syncCounts)
+	s.totals = make(map[string]*syncCounts)
+}
+
+// record adds the counts of a page to its operation and resource type. When
+// the page is the last one, the operation is logged and, if configured, its
+// counts are returned as an annotation.
+func (s *syncStats) record(
+	ctx context.Context,
+	op string,
+	resourceTypeID string,
+	key string,
+	page syncCounts,
+	last bool,
+) *structpb.Struct {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	opKey := op + "/" + resourceTypeID + "/" + key
+	opCounts, ok := s.ops[opKey]
+	if !ok {
+		opCounts = &syncCounts{}
+		s.ops[opKey] = opCounts
+	}
+	opCounts.add(page)
+
+	total, ok := s.totals[resourceTypeID]
+	if !ok {
+		total = &syncCounts{}
+		s.totals[resourceTypeID] = total
+	}
+	total.add(page)
+
+	if !last {
+		return nil
+	}
+	delete(s.ops, opKey)
+
+	fields := []zap.Field{
+		zap.String("op", op),
+		zap.String("resource_type", resourceTypeID),
+		zap.String("key", key),
+	}
+	fields = append(fields, opCounts.fields("")...)
+	fields = append(fields, total.fields("total_")...)
+
+	logger := ctxzap.Extract(ctx)
+	// Entitlement and grant operations run once per resource.
+	if op == "list_resources" {
+		logger.Info("baton-slack: sync operation finished", fields...)
+	} else {
+		logger.Debug("baton-slack: sync operation finished", fields...)
+	}
+
+	if !s.annotate {
+		return nil
+	}
+	stats, err := structpb.NewStruct(map[string]interface{}{
+		"op":            op,
+		"resource_type": resourceTypeID,
+		"counts":        opCounts.toMap(),
+		"totals":        total.toMap(),
+	})
+	if err != nil {
+		return nil
+	}
+	return stats
+}
+
+func appendAnnotation(annos annotations.Annotations, msg *structpb.Struct) annotations.Annotations {
+	annos.Append(msg)
+	return annos
+}
+
+// statsTrackingServer counts what every sync operation emits.
+type statsTrackingServer struct {
+	types.ConnectorServer
+	stats *syncStats
+}
+
+// ListResourceTypes is the first call of every sync.
+func (s *statsTrackingServer) ListResourceTypes(
+	ctx context.Context,
+	request *v2.ResourceTypesServiceListResourceTypesRequest,
+) (*v2.ResourceTypesServiceListResourceTypesResponse, error) {
+	s.stats.reset()
+	return s.ConnectorServer.ListResourceTypes(ctx, request)
+}
+
+func (s *statsTrackingServer) ListResources(
+	ctx context.Context,
+	request *v2.ResourcesServiceListResourcesRequest,
+) (*v2.ResourcesServiceListResourcesResponse, error) {
+	skipped := 0
+	ctx = context.WithValue(ctx, skippedCounterKey{}, &skipped)
+
+	response, err := s.ConnectorServer.ListResources(ctx, request)
+	page := syncCounts{Skipped: skipped}
+	if err != nil {
+		page.Errors = 1
+	} else {
+		page.Resources = len(response.GetList())
+	}
+
+	stats := s.stats.record(
+		ctx,
+		"list_resources",
+		request.GetResourceTypeId(),
+		request.GetParentResourceId().GetResource(),
+		page,
+		err != nil || response.GetNextPageToken() == "",
+	)
+	if stats != nil && response != nil {
+		response.Annotations = appendAnnotation(response.Annotations, stats)
+	}
+	return response, err
+}
+
+func (s *statsTrackingServer) ListEntitlements(
+	ctx context.Context,
+	request *v2.EntitlementsServiceListEntitlementsRequest,
+) (*v2.EntitlementsServiceListEntitlementsResponse, error) {
+	response, err := s.ConnectorServer.ListEntitlements(ctx, request)
+	page := syncCounts{}
+	if err != nil {
+		page.Errors = 1
+	} else {
+		page.Entitlements = len(response.GetList())
+	}
+
+	stats := s.stats.record(
+		ctx,
+		"list_entitlements",
+		request.GetResource().GetId().GetResourceType(),
+		request.GetResource().GetId().GetResource(),
+		page,
+		err != nil || response.GetNextPageToken() == "",
+	)
+	if stats != nil && response != nil {
+		response.Annotations = appendAnnotation(response.Annotations, stats)
+	}
+	return response, err
+}
+
+func (s *statsTrackingServer) ListGrants(
+	ctx context.Context,
+	request *v2.GrantsServiceListGrantsRequest,
+) (*v2.GrantsServiceListGrantsResponse, error) {
+	response, err := s.ConnectorServer.ListGrants(ctx, request)
+	page := syncCounts{}
+	if err != nil {
+		page.Errors = 1
+	} else {
+		page.Grants = len(response.GetList())
+	}
+
+	stats := s.stats.record(
+		ctx,
+		"list_grants",
+		request.GetResource().GetId().GetResourceType(),
+		request.GetResource().GetId().GetResource(),
+		page,
+		err != nil || response.GetNextPageToken() == "",
+	)
+	if stats != nil && response != nil {
+		response.Annotations = appendAnnotation(response.Annotations, stats)
+	}
+	return response, err
+}
@@ -2,6 +2,10 @@ package connector
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -17,6 +21,138 @@ type userResourceType struct {
 	client           *slack.Client
 	enterpriseID     string
 	enterpriseClient *enterprise.Client
+	options          userOptions
+	// adminAPIOnly lists users from admin.users.list only, without users.list
+	// which requires the bot to be installed in the workspace.
+	adminAPIOnly bool
+	// ssoEnabled deletes accounts through SCIM.
+	ssoEnabled bool
+	cache      *syncCache
+}
+
+// userOptions holds configuration and per-workspace data that affect how a
+// Slack user is mapped to a resource. A nil value means defaults.
+type userOptions struct {
+	inactiveDays           int
+	syncLastLogin          bool
+	serviceAccountPatterns []*regexp.Regexp
+	syncPresence           bool
+	syncEmployeeNumbers    bool
+	syncAnalytics          bool
+	excludedProfileFields  map[string]bool
+	emailNormalization     emailNormalization
+	missingEmail           string
+	includeExternalUsers   bool
+	activity               *userActivity
+	presence               map[string]string
+	employeeNumbers        map[string]string
+	analytics              map[string]*memberActivity
+	resolveBotOwners       bool
+	appOwners              map[string]appOwner
+	// duplicateEmails lists, per user, the other active users with the same
+	// email.
+	duplicateEmails map[string][]string
+	// externalUsers holds the users of the workspace that belong to another
+	// organization.
+	externalUsers map[string]bool
+	// deactivations maps the inactive users of the workspace to the time they
+	// were deactivated, zero if unknown. It is only set on Enterprise Grid.
+	deactivations map[string]int
+	// enterpriseID is set on Enterprise Grid.
+	enterpriseID string
+}
+
+// applyProfile adds the optional attributes of the given user to the profile.
+func (u *userOptions) applyProfile(profile map[string]interface{}, userID string) {
+	if u == nil {
+		return
+	}
+
+	if u.inactiveDays > 0 || u.syncLastLogin {
+		for key, value := range u.activity.profile(userID, u.inactiveDays, time.Now()) {
+			profile[key] = value
+		}
+	}
+
+	if presence, ok := u.presence[userID]; ok {
+		profile["presence"] = presence
+	}
+
+	if employeeNumber, ok := u.employeeNumbers[userID]; ok {
+		profile["employee_number"] = employeeNumber
+	}
+
+	if activity, ok := u.analytics[userID]; ok {
+		for key, value := range activity.profile() {
+			profile[key] = value
+		}
+	}
+
+	if duplicates, ok := u.duplicateEmails[userID]; ok {
+		profile["duplicate_email_user_ids"] = strings.Join(duplicates, ",")
+	}
+
+	// This has to run last so that no excluded field can sneak back in.
+	for field := range u.excludedProfileFields {
+		delete(profile, field)
+	}
+}
+
+// lastLogin returns the last access of the given user from the access logs,
+// if it is known.
+func (u *userOptions) lastLogin(userID string) (time.Time, bool) {
+	if u == nil || u.activity == nil {
+		return time.Time{}, false
+	}
+	lastAccess, ok := u.activity.lastAccess[userID]
+	return lastAccess, ok
+}
+
+// deactivatedTs returns the time the given user was deactivated, if known.
+func (u *userOptions) deactivatedTs(userID string) int {
+	if u == nil {
+		return 0
+	}
+	return u.deactivations[userID]
+}
+
+// deletedStatus returns the status of a user that users.list reports as
+// deleted. Slack sets that flag on deactivated users, which can be
+// reactivated, so they are disabled. Only users that admin.users.list doesn't
+// know as deactivated were removed for good.
+func (u *userOptions) deletedStatus(userID string) v2.UserTrait_Status_Status {
+	if u == nil || u.deactivations == nil {
+		return v2.UserTrait_Status_STATUS_DISABLED
+	}
+	if u.deactivatedTs(userID) > 0 {
+		return v2.UserTrait_Status_STATUS_DISABLED
+	}
+	return v2.UserTrait_Status_STATUS_DELETED
+}
+
+// normalizeEmail applies the configured email normalization.
+func (u *userOptions) normalizeEmail(email string) string {
+	if u == nil {
+		return email
+	}
+	return u.emailNormalization.normalize(email)
+}
+
+// isServiceAccount reports whether any of the given names or emails matches
+// one of the configured service account patterns.
+func (u *userOptions) isServiceAccount(values ...string) bool {
+	if u == nil {
+		return false
+	}
+
+	for _, pattern := range u.serviceAccountPatterns {
+		for _, value := range values {
+			if value != "" && pattern.MatchString(value) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (o *userResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -25,18 +161,23 @@ func (o *userResourceType) ResourceType(_ context.Context) *v2.ResourceType {
 
 // Create a new connector resource for a Slack user.
 func userResource(
-	_ context.Context,
+	ctx context.Context,
 	user *slack.User,
 	parentResourceID *v2.ResourceId,
+	options *userOptions,
 ) (*v2.Resource, error) {
+	email, hasEmail := options.resolveEmail(ctx, user.ID, options.normalizeEmail(user.Profile.Email))
 	profile := make(map[string]interface{})
 	profile["first_name"] = user.Profile.FirstName
 	profile["last_name"] = user.Profile.LastName
-	profile["login"] = user.Profile.Email
+	profile["login"] = email
 	profile["workspace"] = user.Profile.Team
 	profile["user_id"] = user.ID
 	profile["status_text"] = user.Profile.StatusText
 	profile["status_emoji"] = user.Profile.StatusEmoji
+	if user.Profile.StatusExpiration > 0 {
+		profile["status_expiration"] = time.Unix(int64(user.Profile.StatusExpiration), 0).Format(time.RFC3339)
+	}
 	profile["is_admin"] = user.IsAdmin
 	profile["is_owner"] = user.IsOwner
 	profile["is_primary_owner"] = user.IsPrimaryOwner
@@ -47,19 +188,39 @@ func userResource(
 	profile["is_ultra_restricted"] = user.IsUltraRestricted
 	profile["is_stranger"] = user.IsStranger
 	profile["is_deleted"] = user.Deleted
+	if user.Enterprise.ID != "" {
+		profile["enterprise_user_id"] = user.Enterprise.ID
+		profile["enterprise_id"] = user.Enterprise.EnterpriseID
+	}
+	if user.IsBot {
+		options.botProfile(profile, user.Profile.BotID, user.Profile.ApiAppID)
+	}
+	if options != nil && options.externalUsers[user.ID] {
+		profile["account_type"] = "external"
+		profile["external_team_id"] = user.TeamID
+	}
+	options.applyProfile(profile, user.ID)
 
 	userStatus := v2.UserTrait_Status_STATUS_ENABLED
 	if user.Deleted {
-		userStatus = v2.UserTrait_Status_STATUS_DELETED
+		userStatus = options.deletedStatus(user.ID)
+		if deactivatedTs := options.deactivatedTs(user.ID); deactivatedTs > 0 {
+			profile["deactivated_at"] = time.Unix(int64(deactivatedTs), 0).Format(time.RFC3339)
+		}
 	}
 
 	userTraitOptions := []resource.UserTraitOption{
 		resource.WithUserProfile(profile),
-		resource.WithEmail(user.Profile.Email, true),
 		resource.WithStatus(userStatus),
 	}
+	if hasEmail {
+		userTraitOptions = append(userTraitOptions, resource.WithEmail(email, true))
+	}
+	if lastLogin, ok := options.lastLogin(user.ID); ok {
+		userTraitOptions = append(userTraitOptions, resource.WithLastLogin(lastLogin))
+	}
 
-	if user.IsBot {
+	if user.IsBot || options.isServiceAccount(user.Name, user.RealName, email) {
 		userTraitOptions = append(
 			userTraitOptions,
 			resource.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_SERVICE),
@@ -76,12 +237,20 @@ func userResource(
 		)
 	}
 
+	resourceOptions := []resource.ResourceOption{
+		resource.WithParentResourceID(parentResourceID),
+		resource.WithAnnotation(userLink(user.Enterprise.EnterpriseID, user.ID)),
+	}
+	if user.Enterprise.ID != "" {
+		resourceOptions = append(resourceOptions, enterpriseUserExternalID(user.Enterprise.ID, user.Enterprise.EnterpriseID))
+	}
+
 	return resource.NewUserResource(
 		user.Name,
 		resourceTypeUser,
 		user.ID,
 		userTraitOptions,
-		resource.WithParentResourceID(parentResourceID),
+		resourceOptions...,
 	)
 }
 
@@ -89,18 +258,30 @@ func userResource(
 // API doesn't return the same values as the user API. We need to create a base
 // resource for users without workspace that are fetched by the Admin API.
 func baseUserResource(
-	_ context.Context,
+	ctx context.Context,
 	user enterprise.UserAdmin,
-	_ *v2.ResourceId,
+	options *userOptions,
 ) (*v2.Resource, error) {
+	email, hasEmail := options.resolveEmail(ctx, user.ID, options.normalizeEmail(user.Email))
 	firstname, lastname := resource.SplitFullName(user.FullName)
 	profile := make(map[string]interface{})
 	profile["first_name"] = firstname
 	profile["last_name"] = lastname
-	profile["login"] = user.Email
+	profile["login"] = email
 	profile["user_id"] = user.ID
 	profile["sso_user"] = user.HasSso
+	// admin.users.list only returns organization-wide user IDs.
+	if options != nil && options.enterpriseID != "" {
+		profile["enterprise_user_id"] = user.ID
+		profile["enterprise_id"] = options.enterpriseID
+	}
+	if !user.IsActive && user.DeactivatedTs > 0 {
+		profile["deactivated_at"] = time.Unix(int64(user.DeactivatedTs), 0).Format(time.RFC3339)
+	}
+	options.applyProfile(profile, user.ID)
 
+	// admin.users.list only returns users that still exist, so an inactive
+	// user is a deactivated one.
 	var userStatus v2.UserTrait_Status_Status
 	if user.IsActive {
 		userStatus = v2.UserTrait_Status_STATUS_ENABLED
@@ -115,13 +296,18 @@ func baseUserResource(
 
 	userTraitOptions := []resource.UserTraitOption{
 		resource.WithUserProfile(profile),
-		resource.WithEmail(user.Email, true),
 		resource.WithStatus(userStatus),
 		resource.WithUserLogin(user.Username),
 		resource.WithSSOStatus(ssoStatus),
 	}
+	if hasEmail {
+		userTraitOptions = append(userTraitOptions, resource.WithEmail(email, true))
+	}
+	if lastLogin, ok := options.lastLogin(user.ID); ok {
+		userTraitOptions = append(userTraitOptions, resource.WithLastLogin(lastLogin))
+	}
 
-	if user.IsBot {
+	if user.IsBot || options.isServiceAccount(user.Username, user.FullName, email) {
 		userTraitOptions = append(
 			userTraitOptions,
 			resource.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_SERVICE),
@@ -138,14 +324,35 @@ func baseUserResource(
 		)
 	}
 
+	var enterpriseID string
+	if options != nil {
+		enterpriseID = options.enterpriseID
+	}
+	resourceOptions := []resource.ResourceOption{
+		resource.WithAnnotation(userLink(enterpriseID, user.ID)),
+	}
+	if enterpriseID != "" {
+		resourceOptions = append(resourceOptions, enterpriseUserExternalID(user.ID, enterpriseID))
+	}
+
 	return resource.NewUserResource(
 		user.FullName,
 		resourceTypeUser,
 		user.ID,
 		userTraitOptions,
+		resourceOptions...,
 	)
 }
 
+// enterpriseUserExternalID identifies a user across the workspaces of an
+// Enterprise Grid organization, so that other data sources can correlate it.
+func enterpriseUserExternalID(enterpriseUserID string, enterpriseID string) resource.ResourceOption {
+	return resource.WithExternalID(&v2.ExternalId{
+		Id:          enterpriseUserID,
+		Description: fmt.Sprintf("Slack Enterprise Grid user of %s", enterpriseID),
+	})
+}
+
 func (o *userResourceType) Entitlements(
 	_ context.Context,
 	_ *v2.Resource,
@@ -191,6 +398,7 @@ func (o *userResourceType) List(
 		pageToken     string
 		nextCursor    string
 		ratelimitData *v2.RateLimitDescription
+		err           error
 	)
 	outputAnnotations := annotations.New()
 	if o.enterpriseID != "" {
@@ -201,30 +409,85 @@ func (o *userResourceType) List(
 
 		// We need to fetch all users because users without workspace won't be
 		// fetched by GetUsersContext.
-		allUsers, nextCursor, ratelimitData, err = o.enterpriseClient.GetUsersAdmin(ctx, bag.PageToken())
+		allUsers, nextCursor, ratelimitData, err = o.cache.getAdminUsers(ctx, o.enterpriseClient, bag.PageToken())
 		outputAnnotations.WithRateLimiting(ratelimitData)
 		if err != nil {
 			return nil, "", outputAnnotations, err
 		}
-		pageToken, err = bag.NextToken(nextCursor)
+		pageToken, err = pkg.NextPageToken(bag, "users", nextCursor)
 		if err != nil {
-			return nil, "", nil, err
+			return nil, "", outputAnnotations, err
+		}
+	}
+
+	var users []slack.User
+	if !o.adminAPIOnly {
+		options := slack.GetUsersOptionTeamID(parentResourceID.Resource)
+		users, err = o.client.GetUsersContext(ctx, options)
+		if err != nil {
+			annos, err := pkg.AnnotationsForError(err)
+			return nil, "", annos, err
 		}
 	}
 
-	options := slack.GetUsersOptionTeamID(parentResourceID.Resource)
-	users, err := o.client.GetUsersContext(ctx, options)
+	userOpts, ratelimitData, err := o.userOptions(ctx, parentResourceID.Resource)
+	outputAnnotations.WithRateLimiting(ratelimitData)
 	if err != nil {
-		annos, err := pkg.AnnotationsForError(err)
-		return nil, "", annos, err
+		return nil, "", outputAnnotations, err
+	}
+
+	if userOpts.syncPresence {
+		userOpts.presence, err = o.userPresence(ctx, users)
+		if err != nil {
+			annos, err := pkg.AnnotationsForError(err)
+			return nil, "", annos, err
+		}
 	}
 
+	if o.enterpriseID != "" && hasDeletedUser(users) {
+		userOpts.deactivations, ratelimitData, err = o.userDeactivations(ctx, parentResourceID.Resource)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+	}
+
+	userOpts.externalUsers = make(map[string]bool)
+	for i := range users {
+		if isExternalUser(&users[i], parentResourceID.Resource, o.enterpriseID, o.cache) {
+			userOpts.externalUsers[users[i].ID] = true
+		}
+	}
+
+	total := len(allUsers) + len(users)
+	allUsers = filterUsers(allUsers, func(user enterprise.UserAdmin) bool {
+		return !userOpts.skipUser(ctx, user.ID, user.Email)
+	})
+	users = filterUsers(users, func(user slack.User) bool {
+		if userOpts.externalUsers[user.ID] && !userOpts.includeExternalUsers {
+			return false
+		}
+		return !userOpts.skipUser(ctx, user.ID, user.Profile.Email)
+	})
+	recordSkipped(ctx, total-len(allUsers)-len(users))
+
+	userOpts.duplicateEmails = o.cache.emails.add(ctx, activeUserEmails(userOpts, allUsers, users))
+
 	// Create a base resource if user has no workspace.
 	rv0, err := pkg.MakeResourceList(
 		ctx,
 		allUsers,
 		nil,
-		baseUserResource,
+		func(
+			ctx context.Context,
+			object enterprise.UserAdmin,
+			_ *v2.ResourceId,
+		) (
+			*v2.Resource,
+			error,
+		) {
+			return baseUserResource(ctx, object, userOpts)
+		},
 	)
 	if err != nil {
 		return nil, "", nil, err
@@ -243,7 +506,7 @@ func (o *userResourceType) List(
 			*v2.Resource,
 			error,
 		) {
-			return userResource(ctx, &object, parentResourceID)
+			return userResource(ctx, &object, parentResourceID, userOpts)
 		},
 	)
 	if err != nil {
@@ -252,15 +515,218 @@ func (o *userResourceType) List(
 	return append(rv0, rv1...), pageToken, outputAnnotations, nil
 }
 
+func filterUsers[T any](users []T, keep func(T) bool) []T {
+	rv := users[:0:0]
+	for _, user := range users {
+		if keep(user) {
+			rv = append(rv, user)
+		}
+	}
+	return rv
+}
+
+// activeUserEmails returns the normalized emails of the active human users.
+func activeUserEmails(
+	options *userOptions,
+	adminUsers []enterprise.UserAdmin,
+	users []slack.User,
+) map[string]string {
+	emails := make(map[string]string, len(adminUsers)+len(users))
+	for _, user := range adminUsers {
+		if user.IsActive && !user.IsBot {
+			emails[user.ID] = options.normalizeEmail(user.Email)
+		}
+	}
+	for _, user := range users {
+		if !user.Deleted && !user.IsBot && !options.externalUsers[user.ID] {
+			emails[user.ID] = options.normalizeEmail(user.Profile.Email)
+		}
+	}
+	return emails
+}
+
+// userOptions returns the options used to map the users of a workspace,
+// fetching the workspace access logs the first time they are needed.
+func (o *userResourceType) userOptions(
+	ctx context.Context,
+	teamID string,
+) (
+	*userOptions,
+	*v2.RateLimitDescription,
+	error,
+) {
+	options := o.options
+	options.enterpriseID = o.enterpriseID
+
+	var ratelimitData *v2.RateLimitDescription
+	if options.syncEmployeeNumbers {
+		if o.cache.employeeNumbers == nil {
+			employeeNumbers, rl, err := fetchEmployeeNumbers(ctx, o.enterpriseClient)
+			ratelimitData = rl
+			if err != nil {
+				return nil, ratelimitData, err
+			}
+			o.cache.employeeNumbers = employeeNumbers
+		}
+		options.employeeNumbers = o.cache.employeeNumbers
+	}
+
+	if options.syncAnalytics {
+		if o.cache.analytics == nil {
+			analytics, rl, err := fetchMemberAnalytics(ctx, o.enterpriseClient, time.Now())
+			ratelimitData = rl
+			if err != nil {
+				return nil, ratelimitData, err
+			}
+			o.cache.analytics = analytics
+		}
+		options.analytics = o.cache.analytics
+	}
+
+	if options.resolveBotOwners {
+		appOwners, ok := o.cache.appOwners.get(teamID)
+		if !ok {
+			var (
+				rl  *v2.RateLimitDescription
+				err error
+			)
+			appOwners, rl, err = fetchAppOwners(ctx, o.enterpriseClient, teamID)
+			ratelimitData = rl
+			if err != nil {
+				return nil, ratelimitData, err
+			}
+			o.cache.appOwners.add(teamID, appOwners)
+		}
+		options.appOwners = appOwners
+	}
+
+	if options.inactiveDays <= 0 && !options.syncLastLogin {
+		return &options, ratelimitData, nil
+	}
+
+	activity, ok := o.cache.activity.get(teamID)
+	if ok {
+		options.activity = activity
+		return &options, ratelimitData, nil
+	}
+
+	var cutoff time.Time
+	if options.inactiveDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -options.inactiveDays)
+	}
+	activity, ratelimitData, err := fetchUserActivity(ctx, o.enterpriseClient, teamID, cutoff, options.syncLastLogin)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+
+	o.cache.activity.add(teamID, activity)
+	options.activity = activity
+	return &options, ratelimitData, nil
+}
+
+// isExternalUser reports whether a user listed in a workspace belongs to
+// another organization, e.g. a member of a channel shared through Slack
+// Connect. Slack only returns a few fields of such users.
+func isExternalUser(user *slack.User, teamID string, enterpriseID string, cache *syncCache) bool {
+	if user.IsStranger {
+		return true
+	}
+	if user.TeamID == "" || user.TeamID == teamID || cache.isWorkspace(user.TeamID) {
+		return false
+	}
+	return enterpriseID == "" || user.Enterprise.EnterpriseID != enterpriseID
+}
+
+func hasDeletedUser(users []slack.User) bool {
+	for _, user := range users {
+		if user.Deleted {
+			return true
+		}
+	}
+	return false
+}
+
+// userDeactivations returns the deactivation time of the inactive users of a
+// workspace, from admin.users.list which, unlike users.list, tells them apart
+// from removed users.
+func (o *userResourceType) userDeactivations(
+	ctx context.Context,
+	teamID string,
+) (
+	map[string]int,
+	*v2.RateLimitDescription,
+	error,
+) {
+	if deactivations, ok := o.cache.deactivations.get(teamID); ok {
+		return deactivations, nil, nil
+	}
+
+	var ratelimitData *v2.RateLimitDescription
+	deactivations := make(map[string]int)
+	err := pkg.ForEachPage("admin users", func(cursor string) (string, error) {
+		users, nextCursor, rl, err := o.enterpriseClient.GetTeamUsersAdmin(ctx, teamID, cursor)
+		ratelimitData = rl
+		if err != nil {
+			return "", err
+		}
+		for _, user := range users {
+			if !user.IsActive {
+				deactivations[user.ID] = user.DeactivatedTs
+			}
+		}
+		return nextCursor, nil
+	})
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+
+	o.cache.deactivations.add(teamID, deactivations)
+	return deactivations, ratelimitData, nil
+}
+
+// userPresence returns the presence of the given users. Slack only exposes
+// presence one user at a time, so results are cached.
+func (o *userResourceType) userPresence(
+	ctx context.Context,
+	users []slack.User,
+) (map[string]string, error) {
+	rv := make(map[string]string, len(users))
+	for _, user := range users {
+		if user.Deleted {
+			continue
+		}
+		if presence, ok := o.cache.presence.get(user.ID); ok {
+			rv[user.ID] = presence
+			continue
+		}
+
+		presence, err := o.client.GetUserPresenceContext(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		o.cache.presence.add(user.ID, presence.Presence)
+		rv[user.ID] = presence.Presence
+	}
+	return rv, nil
+}
+
 func userBuilder(
 	client *slack.Client,
 	enterpriseID string,
 	enterpriseClient *enterprise.Client,
+	options userOptions,
+	adminAPIOnly bool,
+	ssoEnabled bool,
+	cache *syncCache,
 ) *userResourceType {
 	return &userResourceType{
 		resourceType:     resourceTypeUser,
 		client:           client,
 		enterpriseID:     enterpriseID,
 		enterpriseClient: enterpriseClient,
+		options:          options,
+		adminAPIOnly:     adminAPIOnly,
+		ssoEnabled:       ssoEnabled,
+		cache:            cache,
 	}
 }
@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -12,7 +13,11 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/conductorone/baton-slack/pkg"
 	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type userGroupResourceType struct {
@@ -20,6 +25,8 @@ type userGroupResourceType struct {
 	client           *slack.Client
 	enterpriseID     string
 	enterpriseClient *enterprise.Client
+	checkpoint       *checkpointStore
+	cache            *syncCache
 }
 
 func (o *userGroupResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -30,15 +37,25 @@ func userGroupBuilder(
 	client *slack.Client,
 	enterpriseID string,
 	enterpriseClient *enterprise.Client,
+	checkpoint *checkpointStore,
+	cache *syncCache,
 ) *userGroupResourceType {
 	return &userGroupResourceType{
 		resourceType:     resourceTypeUserGroup,
 		client:           client,
 		enterpriseID:     enterpriseID,
 		enterpriseClient: enterpriseClient,
+		checkpoint:       checkpoint,
+		cache:            cache,
 	}
 }
 
+// isOrgUserGroup - organization-level user groups in Enterprise Grid are owned
+// by the enterprise rather than by a single workspace.
+func isOrgUserGroup(userGroup slack.UserGroup, enterpriseID string) bool {
+	return enterpriseID != "" && userGroup.TeamID == enterpriseID
+}
+
 // Create a new connector resource for a Slack user group.
 func userGroupResource(
 	ctx context.Context,
@@ -62,6 +79,36 @@ func userGroupResource(
 	)
 }
 
+// Create a new connector resource for an organization-level user group. These
+// groups are not parented by a workspace since they can be attached to many.
+func orgUserGroupResource(
+	_ context.Context,
+	userGroup slack.UserGroup,
+	channels []enterprise.UserGroupChannel,
+) (*v2.Resource, error) {
+	channelIDs := make([]interface{}, 0, len(channels))
+	for _, channel := range channels {
+		channelIDs = append(channelIDs, channel.ID)
+	}
+
+	return resource.NewGroupResource(
+		userGroup.Name,
+		resourceTypeUserGroup,
+		userGroup.ID,
+		[]resource.GroupTraitOption{
+			resource.WithGroupProfile(
+				map[string]interface{}{
+					"userGroup_id":     userGroup.ID,
+					"userGroup_name":   userGroup.Name,
+					"userGroup_handle": userGroup.Handle,
+					"is_org_level":     true,
+					"channel_ids":      channelIDs,
+				},
+			),
+		},
+	)
+}
+
 func (o *userGroupResourceType) List(
 	ctx context.Context,
 	parentResourceID *v2.ResourceId,
@@ -81,6 +128,16 @@ func (o *userGroupResourceType) List(
 		ratelimitData *v2.RateLimitDescription
 		err           error
 	)
+	// The checkpoint is the source of truth when enabled, so that groups
+	// emitted before a restart are not emitted again.
+	if o.checkpoint != nil {
+		seen := make(map[string]bool)
+		if _, err := o.checkpoint.load(checkpointOrgUserGroupsSeen, &seen); err != nil {
+			return nil, "", nil, err
+		}
+		o.cache.orgUserGroupsSeen = seen
+	}
+
 	outputAnnotations := annotations.New()
 	// We use different method here because we need to pass a teamID, but it's
 	// not supported by the slack-go library.
@@ -105,14 +162,39 @@ func (o *userGroupResourceType) List(
 		}
 	}
 
-	output, err := pkg.MakeResourceList(
-		ctx,
-		userGroups,
-		parentResourceID,
-		userGroupResource,
-	)
-	if err != nil {
-		return nil, "", nil, err
+	output := make([]*v2.Resource, 0, len(userGroups))
+	for _, userGroup := range userGroups {
+		if !isOrgUserGroup(userGroup, o.enterpriseID) {
+			ur, err := userGroupResource(ctx, userGroup, parentResourceID)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			output = append(output, ur)
+			continue
+		}
+
+		// Organization-level user groups show up in every workspace they are
+		// attached to, but we only want to emit them once.
+		if o.cache.orgUserGroupsSeen[userGroup.ID] {
+			continue
+		}
+
+		channels, ratelimitData, err := o.enterpriseClient.GetUserGroupChannels(ctx, userGroup.ID)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+
+		ur, err := orgUserGroupResource(ctx, userGroup, channels)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		output = append(output, ur)
+		o.cache.orgUserGroupsSeen[userGroup.ID] = true
+	}
+
+	if err := o.checkpoint.save(checkpointOrgUserGroupsSeen, o.cache.orgUserGroupsSeen); err != nil {
+		return nil, "", outputAnnotations, err
 	}
 	return output, "", outputAnnotations, nil
 }
@@ -155,13 +237,18 @@ func (o *userGroupResourceType) Entitlements(
 func (o *userGroupResourceType) Grants(
 	ctx context.Context,
 	resource *v2.Resource,
-	_ *pagination.Token,
+	pt *pagination.Token,
 ) (
 	[]*v2.Grant,
 	string,
 	annotations.Annotations,
 	error,
 ) {
+	// Organization-level user groups have no parent workspace.
+	if resource.ParentResourceId == nil {
+		return o.orgGrants(ctx, resource, pt)
+	}
+
 	outputAnnotations := annotations.New()
 	// TODO(marcos): This should use 2D pagination.
 	groupMembers, ratelimitData, err := o.enterpriseClient.GetUserGroupMembers(
@@ -174,21 +261,192 @@ func (o *userGroupResourceType) Grants(
 		return nil, "", outputAnnotations, err
 	}
 
-	var rv []*v2.Grant
+	// The user resources are synced by the user builder, the member IDs are
+	// all we need to build the grants.
+	rv := make([]*v2.Grant, 0, len(groupMembers))
+	for _, member := range groupMembers {
+		userID := &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: member}
+		grant := grant.NewGrant(resource, memberEntitlement, userID, withGrantSource(grantSourceUserGroupMembers))
+		rv = append(rv, grant)
+	}
+
+	return rv, "", outputAnnotations, nil
+}
+
+// orgGrants returns the members of an organization-level user group. Members
+// are granted once against the org-level group instead of once per workspace
+// the group is attached to.
+func (o *userGroupResourceType) orgGrants(
+	ctx context.Context,
+	userGroup *v2.Resource,
+	pt *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	bag, err := pkg.ParsePageToken(pt.Token, &v2.ResourceId{ResourceType: resourceTypeUser.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	outputAnnotations := annotations.New()
+	groupMembers, nextCursor, ratelimitData, err := o.enterpriseClient.GetOrgUserGroupMembers(
+		ctx,
+		userGroup.Id.Resource,
+		bag.PageToken(),
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	pageToken, err := pkg.NextPageToken(bag, "user group members", nextCursor)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	rv := make([]*v2.Grant, 0, len(groupMembers))
 	for _, member := range groupMembers {
-		user, err := o.client.GetUserInfoContext(ctx, member)
+		userID, err := resource.NewResourceID(resourceTypeUser, member)
 		if err != nil {
-			annos, err := pkg.AnnotationsForError(err)
-			return nil, "", annos, err
+			return nil, "", nil, err
 		}
-		ur, err := userResource(ctx, user, resource.Id)
+
+		rv = append(
+			rv,
+			grant.NewGrant(userGroup, memberEntitlement, userID, withGrantSource(grantSourceOrgUserGroupMembers)),
+		)
+	}
+
+	return rv, pageToken, outputAnnotations, nil
+}
+
+// members returns the current members of a user group.
+func (o *userGroupResourceType) members(
+	ctx context.Context,
+	userGroup *v2.Resource,
+	outputAnnotations annotations.Annotations,
+) ([]string, error) {
+	if userGroup.ParentResourceId != nil {
+		members, ratelimitData, err := o.enterpriseClient.GetUserGroupMembers(
+			ctx,
+			userGroup.Id.Resource,
+			userGroup.ParentResourceId.Resource,
+		)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		return members, err
+	}
+
+	var members []string
+	err := pkg.ForEachPage("user group members", func(cursor string) (string, error) {
+		page, nextCursor, ratelimitData, err := o.enterpriseClient.GetOrgUserGroupMembers(ctx, userGroup.Id.Resource, cursor)
+		outputAnnotations.WithRateLimiting(ratelimitData)
 		if err != nil {
-			return nil, "", nil, err
+			return "", err
 		}
+		members = append(members, page...)
+		return nextCursor, nil
+	})
+	return members, err
+}
 
-		grant := grant.NewGrant(resource, memberEntitlement, ur.Id)
-		rv = append(rv, grant)
+// updateMembers replaces the members of a user group.
+func (o *userGroupResourceType) updateMembers(
+	ctx context.Context,
+	userGroup *v2.Resource,
+	members []string,
+	outputAnnotations annotations.Annotations,
+) error {
+	teamID := ""
+	if userGroup.ParentResourceId != nil {
+		teamID = userGroup.ParentResourceId.Resource
+	}
+	ratelimitData, err := o.enterpriseClient.UpdateUserGroupMembers(ctx, userGroup.Id.Resource, teamID, members)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	return err
+}
+
+// Grant adds a user to a user group. usergroups.users.update replaces the
+// whole member list, so the current members are read first.
+func (o *userGroupResourceType) Grant(
+	ctx context.Context,
+	principal *v2.Resource,
+	entitlement *v2.Entitlement,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		logger.Warn(
+			"baton-slack: only users can be added to a user group",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: only users can be added to a user group")
+	}
+
+	outputAnnotations := annotations.New()
+	members, err := o.members(ctx, entitlement.Resource, outputAnnotations)
+	if err != nil {
+		return outputAnnotations, fmt.Errorf("baton-slack: failed to add user to user group: %w", err)
+	}
+
+	if slices.Contains(members, principal.Id.Resource) {
+		outputAnnotations.Append(&v2.GrantAlreadyExists{})
+		return outputAnnotations, nil
+	}
+
+	err = o.updateMembers(ctx, entitlement.Resource, append(members, principal.Id.Resource), outputAnnotations)
+	return grantResult(outputAnnotations, err, "failed to add user to user group")
+}
+
+// Revoke removes a user from a user group. Slack doesn't allow a user group
+// without members, so the last member can't be removed; the user group has
+// to be disabled in Slack instead.
+func (o *userGroupResourceType) Revoke(
+	ctx context.Context,
+	grant *v2.Grant,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	principal := grant.Principal
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		logger.Warn(
+			"baton-slack: only users can be removed from a user group",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: only users can be removed from a user group")
+	}
+
+	outputAnnotations := annotations.New()
+	members, err := o.members(ctx, grant.Entitlement.Resource, outputAnnotations)
+	if err != nil {
+		return outputAnnotations, fmt.Errorf("baton-slack: failed to remove user from user group: %w", err)
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(members), func(member string) bool {
+		return member == principal.Id.Resource
+	})
+	if len(remaining) == len(members) {
+		outputAnnotations.Append(&v2.GrantAlreadyRevoked{})
+		return outputAnnotations, nil
+	}
+	if len(remaining) == 0 {
+		return outputAnnotations, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-slack: can't remove the last member of user group %s, disable the user group in Slack instead",
+			grant.Entitlement.Resource.Id.Resource,
+		)
 	}
 
-	return rv, "", nil, nil
+	err = o.updateMembers(ctx, grant.Entitlement.Resource, remaining, outputAnnotations)
+	return revokeResult(outputAnnotations, err, "failed to remove user from user group")
 }
@@ -0,0 +1,111 @@
+package connector
+
+import (
+	"context"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-slack/pkg"
+	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+)
+
+// stateCheck reports whether the access a Grant or Revoke is about to change
+// is currently in place.
+type stateCheck func(ctx context.Context) (bool, *v2.RateLimitDescription, error)
+
+// alreadyInState runs check before a write when read-before-write is enabled.
+// It reports whether the access is already granted (wantPresent) or already
+// revoked, in which case the write is skipped and the outcome annotated like
+// grantResult and revokeResult do for Slack's own error codes. Not every
+// endpoint reports those, e.g. setting the role a user already holds just
+// succeeds.
+func alreadyInState(
+	ctx context.Context,
+	enabled bool,
+	outputAnnotations annotations.Annotations,
+	wantPresent bool,
+	check stateCheck,
+) (bool, error) {
+	if !enabled {
+		return false, nil
+	}
+
+	present, ratelimitData, err := check(ctx)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	if err != nil {
+		return false, err
+	}
+	if present != wantPresent {
+		return false, nil
+	}
+
+	if wantPresent {
+		outputAnnotations.Append(&v2.GrantAlreadyExists{})
+	} else {
+		outputAnnotations.Append(&v2.GrantAlreadyRevoked{})
+	}
+	return true, nil
+}
+
+// hasAuthPolicy checks whether the user is assigned to the policy.
+func hasAuthPolicy(enterpriseClient *enterprise.Client, policyName string, userID string) stateCheck {
+	return func(ctx context.Context) (bool, *v2.RateLimitDescription, error) {
+		var (
+			found         bool
+			ratelimitData *v2.RateLimitDescription
+		)
+		err := pkg.ForEachPage("auth policy entities", func(cursor string) (string, error) {
+			entities, nextCursor, rl, err := enterpriseClient.GetAuthPolicyEntities(ctx, policyName, cursor)
+			ratelimitData = rl
+			if err != nil {
+				return "", err
+			}
+			for _, entity := range entities {
+				if entity.EntityID == userID {
+					found = true
+					return "", nil
+				}
+			}
+			return nextCursor, nil
+		})
+		return found, ratelimitData, err
+	}
+}
+
+// hasWorkspaceRole checks whether the user holds the given role in the
+// workspace: owner, admin, or one of the guest roles. admin.users.list can't
+// be filtered by user, so this pages through the workspace until the user is
+// found.
+func hasWorkspaceRole(enterpriseClient *enterprise.Client, teamID string, userID string, roleID string) stateCheck {
+	return func(ctx context.Context) (bool, *v2.RateLimitDescription, error) {
+		var (
+			hasRole       bool
+			ratelimitData *v2.RateLimitDescription
+		)
+		err := pkg.ForEachPage("team users", func(cursor string) (string, error) {
+			users, nextCursor, rl, err := enterpriseClient.GetTeamUsersAdmin(ctx, teamID, cursor)
+			ratelimitData = rl
+			if err != nil {
+				return "", err
+			}
+			for _, user := range users {
+				if user.ID != userID {
+					continue
+				}
+				switch roleID {
+				case OwnerRoleID:
+					hasRole = user.IsOwner
+				case AdminRoleID:
+					hasRole = user.IsAdmin
+				case MultiChannelGuestRoleID:
+					hasRole = user.IsRestricted && !user.IsUltraRestricted
+				case SingleChannelGuestRoleID:
+					hasRole = user.IsUltraRestricted
+				}
+				return "", nil
+			}
+			return nextCursor, nil
+		})
+		return hasRole, ratelimitData, err
+	}
+}
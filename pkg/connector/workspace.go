@@ -12,11 +12,11 @@ import (
 	resources "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/conductorone/baton-slack/pkg"
 	enterprise "github.com/conductorone/baton-slack/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/slack-go/slack"
+	"go.uber.org/zap"
 )
 
-var workspacesNameCache = make(map[string]string)
-
 const memberEntitlement = "member"
 
 type workspaceResourceType struct {
@@ -24,6 +24,24 @@ type workspaceResourceType struct {
 	client           *slack.Client
 	enterpriseID     string
 	enterpriseClient *enterprise.Client
+	// adminAPIOnly reads workspace members from admin.users.list instead of
+	// users.list, which requires the bot to be installed in the workspace.
+	adminAPIOnly bool
+	// singleWorkspace is the workspace the bot token belongs to, as reported by
+	// auth.test. When set, it is the only workspace synced.
+	singleWorkspace *slack.Team
+	checkpoint      *checkpointStore
+	cache           *syncCache
+	// highestRoleOnly emits only the most privileged role of every user.
+	highestRoleOnly bool
+	// usersPage and adminUsersPage hold the page of users being emitted in
+	// chunks, see chunkGrants.
+	usersPage      cachedPage[enterprise.User]
+	adminUsersPage cachedPage[enterprise.UserAdmin]
+	// usersPrefetch and adminUsersPrefetch fetch the first page of users of
+	// the next workspaces ahead of their grants, see pagePrefetcher.
+	usersPrefetch      *pagePrefetcher[enterprise.User]
+	adminUsersPrefetch *pagePrefetcher[enterprise.UserAdmin]
 }
 
 func (o *workspaceResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -34,13 +52,42 @@ func workspaceBuilder(
 	client *slack.Client,
 	enterpriseID string,
 	enterpriseClient *enterprise.Client,
+	adminAPIOnly bool,
+	singleWorkspace *slack.Team,
+	checkpoint *checkpointStore,
+	cache *syncCache,
+	highestRoleOnly bool,
+	maxConcurrency int,
 ) *workspaceResourceType {
-	return &workspaceResourceType{
+	o := &workspaceResourceType{
 		resourceType:     resourceTypeWorkspace,
 		client:           client,
 		enterpriseID:     enterpriseID,
 		enterpriseClient: enterpriseClient,
+		adminAPIOnly:     adminAPIOnly,
+		singleWorkspace:  singleWorkspace,
+		checkpoint:       checkpoint,
+		cache:            cache,
+		highestRoleOnly:  highestRoleOnly,
+	}
+	switch {
+	case enterpriseClient == nil:
+	case adminAPIOnly:
+		o.adminUsersPrefetch = newPagePrefetcher(
+			maxConcurrency,
+			func(ctx context.Context, teamID string) ([]enterprise.UserAdmin, string, *v2.RateLimitDescription, error) {
+				return enterpriseClient.GetTeamUsersAdmin(ctx, teamID, "")
+			},
+		)
+	default:
+		o.usersPrefetch = newPagePrefetcher(
+			maxConcurrency,
+			func(ctx context.Context, teamID string) ([]enterprise.User, string, *v2.RateLimitDescription, error) {
+				return enterpriseClient.GetUsers(ctx, teamID, "")
+			},
+		)
 	}
+	return o
 }
 
 // Create a new connector resource for a Slack workspace.
@@ -66,6 +113,11 @@ func workspaceResource(
 			&v2.ChildResourceType{ResourceTypeId: resourceTypeUser.Id},
 			&v2.ChildResourceType{ResourceTypeId: resourceTypeUserGroup.Id},
 			&v2.ChildResourceType{ResourceTypeId: resourceTypeWorkspaceRole.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeExternalOrganization.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeChannel.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeSharedChannel.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeApp.Id},
+			workspaceLink(workspace.ID),
 		),
 	)
 }
@@ -80,6 +132,21 @@ func (o *workspaceResourceType) List(
 	annotations.Annotations,
 	error,
 ) {
+	// Workspaces are the root of the sync, so the first page means a new sync
+	// rather than a resumed one.
+	if pt.Token == "" {
+		o.cache.reset()
+		o.usersPrefetch.reset()
+		o.adminUsersPrefetch.reset()
+		if err := o.checkpoint.reset(); err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	if o.singleWorkspace != nil {
+		return o.listSingle(ctx)
+	}
+
 	bag, err := pkg.ParsePageToken(pt.Token, &v2.ResourceId{ResourceType: resourceTypeWorkspace.Id})
 	if err != nil {
 		return nil, "", nil, err
@@ -106,14 +173,19 @@ func (o *workspaceResourceType) List(
 		}
 	}
 
-	pageToken, err := bag.NextToken(nextCursor)
+	pageToken, err := pkg.NextPageToken(bag, "workspaces", nextCursor)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	// Seed the cache.
 	for _, workspace := range workspaces {
-		workspacesNameCache[workspace.ID] = workspace.Name
+		o.cache.workspaceNames[workspace.ID] = workspace.Name
+		o.usersPrefetch.add(workspace.ID)
+		o.adminUsersPrefetch.add(workspace.ID)
+	}
+	if err := o.checkpoint.save(checkpointWorkspaceNames, o.cache.workspaceNames); err != nil {
+		return nil, "", nil, err
 	}
 
 	output, err := pkg.MakeResourceList(
@@ -129,6 +201,47 @@ func (o *workspaceResourceType) List(
 	return output, pageToken, nil, nil
 }
 
+// listSingle returns the workspace the bot token belongs to. team.info is only
+// used to fill in the domain, auth.test already told us the rest.
+func (o *workspaceResourceType) listSingle(
+	ctx context.Context,
+) (
+	[]*v2.Resource,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	workspace := *o.singleWorkspace
+	teamInfo, err := o.client.GetTeamInfoContext(ctx)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn(
+			"baton-slack: failed to fetch team info, syncing the workspace without its domain",
+			zap.Error(err),
+		)
+	} else {
+		workspace.Name = teamInfo.Name
+		workspace.Domain = teamInfo.Domain
+	}
+
+	o.cache.workspaceNames[workspace.ID] = workspace.Name
+	if err := o.checkpoint.save(checkpointWorkspaceNames, o.cache.workspaceNames); err != nil {
+		return nil, "", nil, err
+	}
+
+	rv, err := workspaceResource(ctx, workspace, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return []*v2.Resource{rv}, "", nil, nil
+}
+
+// canProvisionMembers reports whether workspace membership can be granted and
+// revoked. The admin API that does it is only available to the enterprise
+// token of an Enterprise Grid organization.
+func (o *workspaceResourceType) canProvisionMembers() bool {
+	return o.enterpriseID != ""
+}
+
 func (o *workspaceResourceType) Entitlements(
 	_ context.Context,
 	resource *v2.Resource,
@@ -139,23 +252,30 @@ func (o *workspaceResourceType) Entitlements(
 	annotations.Annotations,
 	error,
 ) {
+	options := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(resourceTypeUser, resourceTypeUserGroup),
+		entitlement.WithDescription(
+			fmt.Sprintf(
+				"Member of the %s workspace",
+				resource.DisplayName,
+			),
+		),
+		entitlement.WithDisplayName(
+			fmt.Sprintf(
+				"%s workspace member",
+				resource.DisplayName,
+			),
+		),
+	}
+	if !o.canProvisionMembers() {
+		options = append(options, entitlement.WithAnnotation(&v2.EntitlementImmutable{}))
+	}
+
 	return []*v2.Entitlement{
 			entitlement.NewAssignmentEntitlement(
 				resource,
 				memberEntitlement,
-				entitlement.WithGrantableTo(resourceTypeUser),
-				entitlement.WithDescription(
-					fmt.Sprintf(
-						"Member of the %s workspace",
-						resource.DisplayName,
-					),
-				),
-				entitlement.WithDisplayName(
-					fmt.Sprintf(
-						"%s workspace member",
-						resource.DisplayName,
-					),
-				),
+				options...,
 			),
 		},
 		"",
@@ -173,127 +293,417 @@ func (o *workspaceResourceType) Grants(
 	annotations.Annotations,
 	error,
 ) {
-	bag, err := pkg.ParsePageToken(pt.Token, &v2.ResourceId{ResourceType: resourceTypeUser.Id})
+	if o.adminAPIOnly {
+		return o.adminGrants(ctx, resource, pt)
+	}
+
+	bag, page, err := parseChunkedPageToken(pt.Token)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	outputAnnotations := annotations.New()
-	users, nextCursor, ratelimitData, err := o.enterpriseClient.GetUsers(
-		ctx,
+	users, nextCursor, err := o.usersPage.get(
 		resource.Id.Resource,
-		bag.PageToken(),
+		page.Cursor,
+		func() ([]enterprise.User, string, error) {
+			if page.Cursor == "" {
+				o.usersPrefetch.start(ctx)
+				if prefetched, ok := o.usersPrefetch.take(ctx, resource.Id.Resource); ok {
+					outputAnnotations.WithRateLimiting(prefetched.ratelimitData)
+					return prefetched.items, prefetched.nextCursor, nil
+				}
+			}
+			users, nextCursor, ratelimitData, err := o.enterpriseClient.GetUsers(
+				ctx,
+				resource.Id.Resource,
+				page.Cursor,
+			)
+			outputAnnotations.WithRateLimiting(ratelimitData)
+			return users, nextCursor, err
+		},
 	)
-	outputAnnotations.WithRateLimiting(ratelimitData)
 	if err != nil {
 		return nil, "", outputAnnotations, err
 	}
 
-	pageToken, err := bag.NextToken(nextCursor)
+	var rv []*v2.Grant
+	// Organization-level user groups attached to the workspace grant their
+	// members access to it. We only need to look these up once per workspace.
+	if o.enterpriseID != "" && pt.Token == "" {
+		userGroupGrants, ratelimitData, err := o.orgUserGroupGrants(ctx, resource)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return nil, "", outputAnnotations, err
+		}
+		rv = append(rv, userGroupGrants...)
+	}
+
+	rv, nextOffset, err := chunkGrants(rv, users, page.Offset, func(user enterprise.User) ([]*v2.Grant, error) {
+		return o.userGrants(ctx, resource, user)
+	})
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	pageToken, err := page.next(bag, nextCursor, nextOffset)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, outputAnnotations, nil
+}
+
+// userGrants returns the workspace membership and role grants of a user.
+func (o *workspaceResourceType) userGrants(
+	ctx context.Context,
+	resource *v2.Resource,
+	user enterprise.User,
+) ([]*v2.Grant, error) {
+	if user.IsStranger {
+		return nil, nil
+	}
+	userID, err := resources.NewResourceID(resourceTypeUser, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	roleIDs := highestRole(workspaceRoleIDs(user), workspaceRolePriority, o.highestRoleOnly)
+
 	var rv []*v2.Grant
-	for _, user := range users {
-		if user.IsStranger {
-			continue
-		}
-		userID, err := resources.NewResourceID(resourceTypeUser, user.ID)
+	for _, roleID := range roleIDs {
+		rr, err := roleResource(ctx, roleID, resource.Id)
 		if err != nil {
-			return nil, "", nil, err
+			return nil, err
 		}
-
-		if user.IsPrimaryOwner {
-			rr, err := roleResource(ctx, PrimaryOwnerRoleID, resource.Id)
-			if err != nil {
-				return nil, "", nil, err
-			}
-			rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
+		source := withGrantSource(grantSourceUsersList)
+		if roleID == SingleChannelGuestRoleID {
+			source = withGrantSourceMetadata(
+				grantSourceUsersList,
+				o.guestChannelMetadata(ctx, resource.Id.Resource, user.ID),
+			)
 		}
+		rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID, source))
+	}
 
-		if user.IsOwner {
-			rr, err := roleResource(ctx, OwnerRoleID, resource.Id)
+	if o.enterpriseID != "" {
+		var enterpriseRoleIDs []string
+		if user.Enterprise.IsPrimaryOwner {
+			enterpriseRoleIDs = append(enterpriseRoleIDs, OrganizationPrimaryOwnerID)
+		}
+		if user.Enterprise.IsOwner {
+			enterpriseRoleIDs = append(enterpriseRoleIDs, OrganizationOwnerID)
+		}
+		if user.Enterprise.IsAdmin {
+			enterpriseRoleIDs = append(enterpriseRoleIDs, OrganizationAdminID)
+		}
+		enterpriseRoleIDs = highestRole(enterpriseRoleIDs, organizationRolePriority, o.highestRoleOnly)
+		for _, roleID := range enterpriseRoleIDs {
+			rr, err := enterpriseRoleResource(ctx, roleID, resource.Id)
 			if err != nil {
-				return nil, "", nil, err
+				return nil, err
 			}
-			rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
+			rv = append(
+				rv,
+				grant.NewGrant(rr, RoleAssignmentEntitlement, userID, withGrantSource(grantSourceUsersListEnterprise)),
+			)
 		}
+	}
 
-		if user.IsAdmin {
-			rr, err := roleResource(ctx, AdminRoleID, resource.Id)
-			if err != nil {
-				return nil, "", nil, err
-			}
-			rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
+	rv = append(rv, grant.NewGrant(resource, memberEntitlement, userID, withGrantSource(grantSourceUsersList)))
+	return rv, nil
+}
+
+// workspaceRoleIDs returns the IDs of every workspace role the user holds.
+func workspaceRoleIDs(user enterprise.User) []string {
+	var roleIDs []string
+	if user.IsPrimaryOwner {
+		roleIDs = append(roleIDs, PrimaryOwnerRoleID)
+	}
+	if user.IsOwner {
+		roleIDs = append(roleIDs, OwnerRoleID)
+	}
+	if user.IsAdmin {
+		roleIDs = append(roleIDs, AdminRoleID)
+	}
+	if user.IsRestricted {
+		if user.IsUltraRestricted {
+			roleIDs = append(roleIDs, SingleChannelGuestRoleID)
+		} else {
+			roleIDs = append(roleIDs, MultiChannelGuestRoleID)
 		}
+	}
+	if user.IsInvitedUser {
+		roleIDs = append(roleIDs, InvitedMemberRoleID)
+	}
+	if !user.IsRestricted && !user.IsUltraRestricted && !user.IsInvitedUser && !user.IsBot && !user.Deleted {
+		roleIDs = append(roleIDs, MemberRoleID)
+	}
+	if user.IsBot {
+		roleIDs = append(roleIDs, BotRoleID)
+	}
+	return roleIDs
+}
 
-		if user.IsRestricted {
-			if user.IsUltraRestricted {
-				rr, err := roleResource(ctx, SingleChannelGuestRoleID, resource.Id)
-				if err != nil {
-					return nil, "", nil, err
-				}
-				rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
-			} else {
-				rr, err := roleResource(ctx, MultiChannelGuestRoleID, resource.Id)
-				if err != nil {
-					return nil, "", nil, err
+// adminGrants is the admin API counterpart of Grants. admin.users.list doesn't
+// report invitations or organization roles, so only the workspace roles it
+// exposes are granted.
+func (o *workspaceResourceType) adminGrants(
+	ctx context.Context,
+	resource *v2.Resource,
+	pt *pagination.Token,
+) (
+	[]*v2.Grant,
+	string,
+	annotations.Annotations,
+	error,
+) {
+	bag, page, err := parseChunkedPageToken(pt.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	outputAnnotations := annotations.New()
+	users, nextCursor, err := o.adminUsersPage.get(
+		resource.Id.Resource,
+		page.Cursor,
+		func() ([]enterprise.UserAdmin, string, error) {
+			if page.Cursor == "" {
+				o.adminUsersPrefetch.start(ctx)
+				if prefetched, ok := o.adminUsersPrefetch.take(ctx, resource.Id.Resource); ok {
+					outputAnnotations.WithRateLimiting(prefetched.ratelimitData)
+					return prefetched.items, prefetched.nextCursor, nil
 				}
-				rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
 			}
+			users, nextCursor, ratelimitData, err := o.enterpriseClient.GetTeamUsersAdmin(
+				ctx,
+				resource.Id.Resource,
+				page.Cursor,
+			)
+			outputAnnotations.WithRateLimiting(ratelimitData)
+			return users, nextCursor, err
+		},
+	)
+	if err != nil {
+		return nil, "", outputAnnotations, err
+	}
+
+	var rv []*v2.Grant
+	if pt.Token == "" {
+		userGroupGrants, ratelimitData, err := o.orgUserGroupGrants(ctx, resource)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil {
+			return nil, "", outputAnnotations, err
 		}
+		rv = append(rv, userGroupGrants...)
+	}
 
-		if user.IsInvitedUser {
-			rr, err := roleResource(ctx, InvitedMemberRoleID, resource.Id)
-			if err != nil {
-				return nil, "", nil, err
-			}
-			rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
+	rv, nextOffset, err := chunkGrants(rv, users, page.Offset, func(user enterprise.UserAdmin) ([]*v2.Grant, error) {
+		return o.adminUserGrants(ctx, resource, user)
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	pageToken, err := page.next(bag, nextCursor, nextOffset)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, pageToken, outputAnnotations, nil
+}
+
+// adminUserGrants returns the workspace membership and role grants of a user
+// listed by admin.users.list.
+func (o *workspaceResourceType) adminUserGrants(
+	ctx context.Context,
+	resource *v2.Resource,
+	user enterprise.UserAdmin,
+) ([]*v2.Grant, error) {
+	userID, err := resources.NewResourceID(resourceTypeUser, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var roleIDs []string
+	if user.IsPrimaryOwner {
+		roleIDs = append(roleIDs, PrimaryOwnerRoleID)
+	}
+	if user.IsOwner {
+		roleIDs = append(roleIDs, OwnerRoleID)
+	}
+	if user.IsAdmin {
+		roleIDs = append(roleIDs, AdminRoleID)
+	}
+	if user.IsRestricted {
+		if user.IsUltraRestricted {
+			roleIDs = append(roleIDs, SingleChannelGuestRoleID)
+		} else {
+			roleIDs = append(roleIDs, MultiChannelGuestRoleID)
 		}
+	}
+	if !user.IsRestricted && !user.IsUltraRestricted && !user.IsBot && user.IsActive {
+		roleIDs = append(roleIDs, MemberRoleID)
+	}
+	if user.IsBot {
+		roleIDs = append(roleIDs, BotRoleID)
+	}
+	roleIDs = highestRole(roleIDs, workspaceRolePriority, o.highestRoleOnly)
 
-		if !user.IsRestricted && !user.IsUltraRestricted && !user.IsInvitedUser && !user.IsBot && !user.Deleted {
-			rr, err := roleResource(ctx, MemberRoleID, resource.Id)
-			if err != nil {
-				return nil, "", nil, err
-			}
-			rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
+	var rv []*v2.Grant
+	for _, roleID := range roleIDs {
+		rr, err := roleResource(ctx, roleID, resource.Id)
+		if err != nil {
+			return nil, err
+		}
+		source := withGrantSource(grantSourceAdminUsersList)
+		if roleID == SingleChannelGuestRoleID {
+			source = withGrantSourceMetadata(
+				grantSourceAdminUsersList,
+				o.guestChannelMetadata(ctx, resource.Id.Resource, user.ID),
+			)
 		}
+		rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID, source))
+	}
 
-		if user.IsBot {
-			rr, err := roleResource(ctx, BotRoleID, resource.Id)
-			if err != nil {
-				return nil, "", nil, err
-			}
-			rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
+	rv = append(rv, grant.NewGrant(resource, memberEntitlement, userID, withGrantSource(grantSourceAdminUsersList)))
+	return rv, nil
+}
+
+// orgUserGroupGrants returns a grant for every organization-level user group
+// that is attached to the given workspace.
+func (o *workspaceResourceType) orgUserGroupGrants(
+	ctx context.Context,
+	resource *v2.Resource,
+) (
+	[]*v2.Grant,
+	*v2.RateLimitDescription,
+	error,
+) {
+	userGroups, ratelimitData, err := o.enterpriseClient.GetUserGroups(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, ratelimitData, err
+	}
+
+	var rv []*v2.Grant
+	for _, userGroup := range userGroups {
+		if !isOrgUserGroup(userGroup, o.enterpriseID) {
+			continue
 		}
 
-		if o.enterpriseID != "" {
-			if user.Enterprise.IsPrimaryOwner {
-				rr, err := enterpriseRoleResource(ctx, OrganizationPrimaryOwnerID, resource.Id)
-				if err != nil {
-					return nil, "", nil, err
-				}
-				rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
-			}
-			if user.Enterprise.IsOwner {
-				rr, err := enterpriseRoleResource(ctx, OrganizationOwnerID, resource.Id)
-				if err != nil {
-					return nil, "", nil, err
-				}
-				rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
-			}
-			if user.Enterprise.IsAdmin {
-				rr, err := enterpriseRoleResource(ctx, OrganizationAdminID, resource.Id)
-				if err != nil {
-					return nil, "", nil, err
-				}
-				rv = append(rv, grant.NewGrant(rr, RoleAssignmentEntitlement, userID))
-			}
+		userGroupID, err := resources.NewResourceID(resourceTypeUserGroup, userGroup.ID)
+		if err != nil {
+			return nil, ratelimitData, err
 		}
 
-		rv = append(rv, grant.NewGrant(resource, memberEntitlement, userID))
+		rv = append(
+			rv,
+			grant.NewGrant(
+				resource,
+				memberEntitlement,
+				userGroupID,
+				withGrantSource(grantSourceOrgUserGroupWorkspace),
+				grant.WithAnnotation(
+					&v2.GrantExpandable{
+						EntitlementIds: []string{
+							fmt.Sprintf("%s:%s:%s", resourceTypeUserGroup.Id, userGroup.ID, memberEntitlement),
+						},
+					},
+				),
+			),
+		)
 	}
 
-	return rv, pageToken, outputAnnotations, nil
+	return rv, ratelimitData, nil
+}
+
+func (o *workspaceResourceType) Grant(
+	ctx context.Context,
+	principal *v2.Resource,
+	entitlement *v2.Entitlement,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	if !o.canProvisionMembers() {
+		logger.Warn(
+			"baton-slack: workspace membership can only be provisioned on Enterprise Grid",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: workspace membership can only be provisioned on Enterprise Grid")
+	}
+
+	outputAnnotations := annotations.New()
+	switch principal.Id.ResourceType {
+	case resourceTypeUser.Id:
+		ratelimitData, err := o.enterpriseClient.AssignUserToTeam(
+			ctx,
+			entitlement.Resource.Id.Resource,
+			principal.Id.Resource,
+		)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		return grantResult(outputAnnotations, err, "failed to add user to workspace")
+	case resourceTypeUserGroup.Id:
+	default:
+		logger.Warn(
+			"baton-slack: only users and organization user groups can be added to a workspace",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-slack: only users and organization user groups can be added to a workspace")
+	}
+
+	ratelimitData, err := o.enterpriseClient.AddUserGroupToTeam(
+		ctx,
+		principal.Id.Resource,
+		entitlement.Resource.Id.Resource,
+	)
+	outputAnnotations.WithRateLimiting(ratelimitData)
+	return grantResult(outputAnnotations, err, "failed to attach user group to workspace")
+}
+
+// Revoke removes a user from a workspace. Slack doesn't expose an admin API to
+// detach an organization-level user group from a workspace, so this has to be
+// done from the admin console.
+func (o *workspaceResourceType) Revoke(
+	ctx context.Context,
+	grant *v2.Grant,
+) (
+	annotations.Annotations,
+	error,
+) {
+	logger := ctxzap.Extract(ctx)
+
+	if grant.Principal.Id.ResourceType == resourceTypeUser.Id {
+		if !o.canProvisionMembers() {
+			logger.Warn(
+				"baton-slack: workspace membership can only be provisioned on Enterprise Grid",
+				zap.String("principal_id", grant.Principal.Id.Resource),
+			)
+			return nil, fmt.Errorf("baton-slack: workspace membership can only be provisioned on Enterprise Grid")
+		}
+
+		outputAnnotations := annotations.New()
+		ratelimitData, err := o.enterpriseClient.RemoveUserFromTeam(
+			ctx,
+			grant.Entitlement.Resource.Id.Resource,
+			grant.Principal.Id.Resource,
+		)
+		outputAnnotations.WithRateLimiting(ratelimitData)
+		if err != nil && notMemberCodes[slackErrorCode(err)] {
+			outputAnnotations.Append(&v2.GrantAlreadyRevoked{})
+			return outputAnnotations, nil
+		}
+		return revokeResult(outputAnnotations, err, "failed to remove user from workspace")
+	}
+
+	logger.Warn(
+		"baton-slack: detaching a user group from a workspace is not supported",
+		zap.String("principal_type", grant.Principal.Id.ResourceType),
+		zap.String("principal_id", grant.Principal.Id.Resource),
+	)
+	return nil, fmt.Errorf("baton-slack: detaching a user group from a workspace is not supported by the Slack API")
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,9 +13,137 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/slack-go/slack"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// Slack error codes returned when the token itself is no longer usable.
+const (
+	ErrorCodeAccountInactive = "account_inactive"
+	ErrorCodeTokenRevoked    = "token_revoked"
+)
+
+// TokenHealthError - the token used by the connector was deactivated or
+// revoked. No request can succeed until the token is replaced, so this is
+// surfaced with remediation guidance instead of a generic auth failure.
+type TokenHealthError struct {
+	Code string
+}
+
+func (e *TokenHealthError) Error() string {
+	switch e.Code {
+	case ErrorCodeAccountInactive:
+		return "baton-slack: the Slack token belongs to a deactivated user or a deleted workspace. " +
+			"Reinstall the Slack app as an active admin and update the configured token"
+	default:
+		return "baton-slack: the Slack token has been revoked. " +
+			"Reinstall the Slack app to issue a new token and update the configured token"
+	}
+}
+
+// GRPCStatus lets the SDK report this error with the proper status code.
+func (e *TokenHealthError) GRPCStatus() *status.Status {
+	return status.New(codes.Unauthenticated, e.Error())
+}
+
+// NewTokenHealthError returns a TokenHealthError if the given Slack error code
+// means the token can no longer be used, otherwise nil.
+func NewTokenHealthError(code string) error {
+	switch code {
+	case ErrorCodeAccountInactive, ErrorCodeTokenRevoked:
+		return &TokenHealthError{Code: code}
+	default:
+		return nil
+	}
+}
+
+// WrapTokenError - replace errors returned by the slack-go client for dead
+// tokens with a TokenHealthError, leaving every other error untouched.
+func WrapTokenError(err error) error {
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) {
+		if tokenErr := NewTokenHealthError(slackErr.Err); tokenErr != nil {
+			return tokenErr
+		}
+	}
+	return err
+}
+
+// CheckCursor - under some error conditions Slack keeps returning the cursor
+// it was called with, which would make us page through the same results
+// forever. Returns an error if that happens.
+func CheckCursor(listing string, cursor string, nextCursor string) error {
+	if nextCursor != "" && nextCursor == cursor {
+		return fmt.Errorf(
+			"baton-slack: error listing %s: Slack returned the same cursor again, aborting to avoid an endless loop",
+			listing,
+		)
+	}
+	return nil
+}
+
+// NextPageToken - returns the token for the page after the one the bag points
+// at, given the cursor Slack returned with it. An empty cursor ends the
+// listing.
+func NextPageToken(bag *pagination.Bag, listing string, nextCursor string) (string, error) {
+	if err := CheckCursor(listing, bag.PageToken(), nextCursor); err != nil {
+		return "", err
+	}
+	return bag.NextToken(nextCursor)
+}
+
+// ForEachPage - calls fetch with every cursor of a listing, starting with the
+// empty one, until it returns an empty cursor. It is meant for listings read
+// eagerly rather than page by page through the SDK.
+func ForEachPage(listing string, fetch func(cursor string) (string, error)) error {
+	cursor := ""
+	for {
+		nextCursor, err := fetch(cursor)
+		if err != nil {
+			return err
+		}
+		if nextCursor == "" {
+			return nil
+		}
+		if err := CheckCursor(listing, cursor, nextCursor); err != nil {
+			return err
+		}
+		cursor = nextCursor
+	}
+}
+
+// ParseOffsetToken - returns the offset and limit of an offset-paginated
+// listing (SCIM), in that order. A missing token starts at startingOffset.
+func ParseOffsetToken(pToken *pagination.Token, startingOffset int, defaultLimit int) (int, int, error) {
+	offset, limit := startingOffset, defaultLimit
+	if pToken == nil {
+		return offset, limit, nil
+	}
+
+	if pToken.Size > 0 {
+		limit = pToken.Size
+	}
+	if pToken.Token != "" {
+		parsedOffset, err := strconv.Atoi(pToken.Token)
+		if err != nil {
+			return 0, 0, fmt.Errorf("baton-slack: invalid page token %q: %w", pToken.Token, err)
+		}
+		offset = parsedOffset
+	}
+	return offset, limit, nil
+}
+
+// NextOffsetToken - returns the token for the page after the one starting at
+// offset, or an empty token if that page is the last one.
+func NextOffsetToken(offset int, limit int, total int) string {
+	nextOffset := offset + limit
+	if nextOffset >= total {
+		return ""
+	}
+	return strconv.Itoa(nextOffset)
+}
+
 type EnterpriseRolesPagination struct {
 	Cursor   string          `json:"cursor"`
 	FoundMap map[string]bool `json:"foundMap"`
@@ -127,5 +256,5 @@ func AnnotationsForError(err error) (annotations.Annotations, error) {
 		)
 		return annos, nil
 	}
-	return annos, err
+	return annos, WrapTokenError(err)
 }
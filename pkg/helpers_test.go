@@ -0,0 +1,229 @@
+package pkg
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+var testResourceID = &v2.ResourceId{ResourceType: "workspace", Resource: "T123"}
+
+// pageToken returns the token the SDK passes back for the page at cursor.
+func pageToken(t *testing.T, cursor string) string {
+	t.Helper()
+
+	if cursor == "" {
+		return ""
+	}
+	bag := &pagination.Bag{}
+	bag.Push(pagination.PageState{
+		ResourceTypeID: testResourceID.ResourceType,
+		ResourceID:     testResourceID.Resource,
+		Token:          cursor,
+	})
+	token, err := bag.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestNextPageToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		cursor     string
+		nextCursor string
+		wantCursor string
+		wantEnd    bool
+		wantErr    bool
+	}{
+		{
+			name:       "first page",
+			nextCursor: "c1",
+			wantCursor: "c1",
+		},
+		{
+			name:       "resumed page",
+			cursor:     "c1",
+			nextCursor: "c2",
+			wantCursor: "c2",
+		},
+		{
+			name:    "empty next cursor on the first page",
+			wantEnd: true,
+		},
+		{
+			name:    "empty next cursor on a resumed page",
+			cursor:  "c1",
+			wantEnd: true,
+		},
+		{
+			name:       "repeated cursor",
+			cursor:     "c1",
+			nextCursor: "c1",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bag, err := ParsePageToken(pageToken(t, tt.cursor), testResourceID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bag.PageToken() != tt.cursor {
+				t.Fatalf("resumed at cursor %q, want %q", bag.PageToken(), tt.cursor)
+			}
+
+			token, err := NextPageToken(bag, "users", tt.nextCursor)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error for a repeated cursor")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantEnd {
+				if token != "" {
+					t.Fatalf("got token %q, want the end of the listing", token)
+				}
+				return
+			}
+
+			next, err := ParsePageToken(token, testResourceID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next.PageToken() != tt.wantCursor {
+				t.Errorf("next cursor %q, want %q", next.PageToken(), tt.wantCursor)
+			}
+			if next.ResourceID() != testResourceID.Resource {
+				t.Errorf("next resource ID %q, want %q", next.ResourceID(), testResourceID.Resource)
+			}
+		})
+	}
+}
+
+func TestForEachPage(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+
+	tests := []struct {
+		name string
+		// pages maps every cursor to the cursor Slack returns with it.
+		pages       map[string]string
+		fetchErr    error
+		wantCursors []string
+		wantErr     bool
+	}{
+		{
+			name:        "single page",
+			pages:       map[string]string{"": ""},
+			wantCursors: []string{""},
+		},
+		{
+			name:        "several pages",
+			pages:       map[string]string{"": "c1", "c1": "c2", "c2": ""},
+			wantCursors: []string{"", "c1", "c2"},
+		},
+		{
+			name:        "repeated cursor",
+			pages:       map[string]string{"": "c1", "c1": "c1"},
+			wantCursors: []string{"", "c1"},
+			wantErr:     true,
+		},
+		{
+			name:        "fetch error",
+			pages:       map[string]string{"": "c1"},
+			fetchErr:    errFetch,
+			wantCursors: []string{""},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cursors []string
+			err := ForEachPage("users", func(cursor string) (string, error) {
+				cursors = append(cursors, cursor)
+				if len(cursors) > len(tt.pages)+1 {
+					t.Fatal("listing didn't end")
+				}
+				return tt.pages[cursor], tt.fetchErr
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.fetchErr != nil && !errors.Is(err, tt.fetchErr) {
+				t.Errorf("got error %v, want %v", err, tt.fetchErr)
+			}
+			if !slices.Equal(cursors, tt.wantCursors) {
+				t.Errorf("fetched cursors %q, want %q", cursors, tt.wantCursors)
+			}
+		})
+	}
+}
+
+func TestOffsetTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      *pagination.Token
+		total      int
+		wantOffset int
+		wantLimit  int
+		wantNext   string
+		wantErr    bool
+	}{
+		{
+			name:       "no token",
+			total:      250,
+			wantOffset: 1,
+			wantLimit:  100,
+			wantNext:   "101",
+		},
+		{
+			name:       "resumed token",
+			token:      &pagination.Token{Token: "101", Size: 50},
+			total:      250,
+			wantOffset: 101,
+			wantLimit:  50,
+			wantNext:   "151",
+		},
+		{
+			name:       "last page",
+			token:      &pagination.Token{Token: "201"},
+			total:      250,
+			wantOffset: 201,
+			wantLimit:  100,
+			wantNext:   "",
+		},
+		{
+			name:    "invalid token",
+			token:   &pagination.Token{Token: "next"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, limit, err := ParseOffsetToken(tt.token, 1, 100)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if offset != tt.wantOffset || limit != tt.wantLimit {
+				t.Errorf("got offset %d and limit %d, want %d and %d", offset, limit, tt.wantOffset, tt.wantLimit)
+			}
+			if next := NextOffsetToken(offset, limit, tt.total); next != tt.wantNext {
+				t.Errorf("got next token %q, want %q", next, tt.wantNext)
+			}
+		})
+	}
+}